@@ -0,0 +1,71 @@
+// Command gocsv works with CSV files.
+//
+// Usage:
+//
+//	gocsv infer [-name Record] [-package pkg] [-rows n] [file.csv]
+//
+// The infer command reads a sample of the CSV file, or of the standard input,
+// infers the type of each column and prints a Go struct to decode its rows.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/gocarina/gocsv"
+)
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+	var err error
+	switch flag.Arg(0) {
+	case "infer":
+		err = infer(flag.Args()[1:])
+	default:
+		usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "gocsv:", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: gocsv infer [-name Record] [-package pkg] [-rows n] [file.csv]")
+}
+
+func infer(args []string) error {
+	flags := flag.NewFlagSet("infer", flag.ExitOnError)
+	name := flags.String("name", "Record", "name of the struct")
+	pkg := flags.String("package", "", "package of the Go file to print, only the struct if empty")
+	rows := flags.Int("rows", 1000, "number of rows to read, all of them if 0")
+	flags.Parse(args)
+
+	var in io.Reader = os.Stdin
+	if flags.NArg() > 0 {
+		f, err := os.Open(flags.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+	schema, err := gocsv.InferSchema(in, *rows)
+	if err != nil {
+		return err
+	}
+	src, err := gocsv.GenerateStruct(schema, *pkg, *name)
+	if err != nil {
+		return err
+	}
+	_, err = os.Stdout.Write(src)
+	return err
+}
@@ -0,0 +1,17 @@
+// Command gocsvlint checks the csv tags of the struct types passed to the
+// gocsv functions. It is run by go vet:
+//
+//	go install github.com/gocarina/gocsv/cmd/gocsvlint
+//	go vet -vettool=$(which gocsvlint) ./...
+//
+// See the gocsvlint package for the reported mistakes.
+package main
+
+import (
+	"github.com/gocarina/gocsv/gocsvlint"
+	"golang.org/x/tools/go/analysis/unitchecker"
+)
+
+func main() {
+	unitchecker.Main(gocsvlint.Analyzer)
+}
@@ -0,0 +1,106 @@
+package gocsv
+
+import (
+	"bufio"
+	"compress/bzip2"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// Compression is a compression format of CSV files.
+type Compression int
+
+const (
+	// NoCompression is plain CSV.
+	NoCompression Compression = iota
+	// Gzip is the gzip format, with the extension .gz.
+	Gzip
+	// Zlib is the zlib format, with the extension .zz or .zlib.
+	Zlib
+	// Bzip2 is the bzip2 format, with the extension .bz2. It can only be decompressed.
+	Bzip2
+)
+
+var errBzip2Writer = errors.New("gocsv: bzip2 can only be decompressed")
+
+// compressionOf returns the compression of the file extension of path.
+func compressionOf(path string) (Compression, bool) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".gz", ".gzip":
+		return Gzip, true
+	case ".zz", ".zlib":
+		return Zlib, true
+	case ".bz2":
+		return Bzip2, true
+	}
+	return NoCompression, false
+}
+
+// detectCompression returns the compression of the data starting with magic.
+// Zlib is not detected: many two-byte texts, such as "H,", are valid zlib headers.
+func detectCompression(magic []byte) Compression {
+	switch {
+	case len(magic) >= 2 && magic[0] == 0x1f && magic[1] == 0x8b:
+		return Gzip
+	case len(magic) >= 4 && string(magic[:3]) == "BZh" && '1' <= magic[3] && magic[3] <= '9':
+		return Bzip2
+	}
+	return NoCompression
+}
+
+// decompress returns the decompressed data of in, compressed with c or, when
+// detect, with the compression of its magic bytes. Errors of the compressed
+// format are returned by the first Read.
+func decompress(in io.Reader, c Compression, detect bool) io.Reader {
+	if detect {
+		buffered := bufio.NewReader(in)
+		magic, _ := buffered.Peek(4)
+		in, c = buffered, detectCompression(magic)
+	}
+	var (
+		r   io.Reader
+		err error
+	)
+	switch c {
+	case Gzip:
+		r, err = gzip.NewReader(in)
+	case Zlib:
+		r, err = zlib.NewReader(in)
+	case Bzip2:
+		r = bzip2.NewReader(in)
+	default:
+		r = in
+	}
+	if err != nil {
+		return errReader{err}
+	}
+	return r
+}
+
+// compress returns a writer compressing to out with c, and the func to call
+// once done writing.
+func compress(out io.Writer, c Compression) (io.Writer, func() error, error) {
+	switch c {
+	case Gzip:
+		w := gzip.NewWriter(out)
+		return w, w.Close, nil
+	case Zlib:
+		w := zlib.NewWriter(out)
+		return w, w.Close, nil
+	case Bzip2:
+		return nil, nil, errBzip2Writer
+	}
+	return out, func() error { return nil }, nil
+}
+
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
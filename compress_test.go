@@ -0,0 +1,127 @@
+package gocsv
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/csv"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// bzip2Sample is "Baz,BAR\nf,1\ne,x\n" compressed with bzip2.
+var bzip2Sample = []byte("\x42\x5a\x68\x39\x31\x41\x59\x26\x53\x59\x25\x01\x6b\xa7\x00\x00\x04\xdf\x80\x00\x10\x00\x04\x20\x00\x30\x00\x10\x00\x23\x00\x00\x50\x20\x00\x22\x06\x81\x90\x80\x69\xa6\x82\x30\xa5\xa6\x38\x89\x60\xbc\x5d\xc9\x14\xe1\x42\x40\x94\x05\xae\x9c")
+
+func TestMarshalPath(t *testing.T) {
+	dir := t.TempDir()
+	samples := []MultiTagSample{{Foo: "f", Bar: 1}, {Foo: "e", Bar: 3}}
+	for _, name := range []string{"samples.csv", "samples.csv.gz", "samples.csv.zz"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name)
+			if err := MarshalPath(samples, path); err != nil {
+				t.Fatal(err)
+			}
+			var decoded []MultiTagSample
+			if err := UnmarshalPath(path, &decoded); err != nil {
+				t.Fatal(err)
+			}
+			if len(decoded) != 2 || decoded[0] != samples[0] || decoded[1] != samples[1] {
+				t.Fatalf("unexpected samples %+v", decoded)
+			}
+		})
+	}
+
+	// Compressed files are detected by their magic bytes.
+	path := filepath.Join(dir, "samples.gz.csv")
+	if err := MarshalPath(samples, path, Compress(Gzip)); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(raw, []byte{0x1f, 0x8b}) {
+		t.Fatalf("expected gzip data, got %q", raw)
+	}
+	var decoded []MultiTagSample
+	if err := UnmarshalPath(path, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 2 {
+		t.Fatalf("unexpected samples %+v", decoded)
+	}
+
+	if err := MarshalPath(samples, filepath.Join(dir, "samples.csv.bz2")); err != errBzip2Writer {
+		t.Fatalf("expected %v, got %v", errBzip2Writer, err)
+	}
+}
+
+func TestDetectCompression(t *testing.T) {
+	in := "Baz,BAR\nf,1\ne,x\n"
+	gz := bytes.Buffer{}
+	gw := gzip.NewWriter(&gz)
+	io.WriteString(gw, in)
+	gw.Close()
+
+	for name, data := range map[string][]byte{"plain": []byte(in), "gzip": gz.Bytes(), "bzip2": bzip2Sample} {
+		t.Run(name, func(t *testing.T) {
+			var samples []MultiTagSample
+			err := UnmarshalDecoder(NewDecoder(bytes.NewReader(data), DetectCompression()), &samples)
+			// The line numbers are those of the uncompressed CSV.
+			var parseErr *csv.ParseError
+			if !errors.As(err, &parseErr) || parseErr.Line != 3 || parseErr.Column != 2 {
+				t.Fatalf("expected an error on line 3, column 2, got %v", err)
+			}
+		})
+	}
+}
+
+func TestDetectCompression_zlibLikeText(t *testing.T) {
+	// "H," is a valid zlib header, but plain text.
+	var samples []struct {
+		H string `csv:"H"`
+		X string `csv:"x"`
+	}
+	if err := UnmarshalDecoder(NewDecoder(strings.NewReader("H,x\na,b\n"), DetectCompression()), &samples); err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 1 || samples[0].X != "b" {
+		t.Fatalf("unexpected %+v", samples)
+	}
+}
+
+func TestDecompress(t *testing.T) {
+	in := "Baz,BAR\nf,1\n"
+	zz := bytes.Buffer{}
+	zw := zlib.NewWriter(&zz)
+	io.WriteString(zw, in)
+	zw.Close()
+	var samples []MultiTagSample
+	if err := UnmarshalDecoder(NewDecoder(&zz, Decompress(Zlib)), &samples); err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 1 || samples[0].Foo != "f" {
+		t.Fatalf("unexpected %+v", samples)
+	}
+
+	gz := bytes.Buffer{}
+	gw := gzip.NewWriter(&gz)
+	io.WriteString(gw, in)
+	gw.Close()
+	err := UnmarshalDecoder(NewDecoder(&gz, DetectCompression(), Decompress(NoCompression)), &samples)
+	if err == nil {
+		t.Fatal("expected the gzip data to be read as it is")
+	}
+}
+
+func TestDetectCompression_corrupt(t *testing.T) {
+	var samples []MultiTagSample
+	err := UnmarshalDecoder(NewDecoder(strings.NewReader("\x1f\x8b\x08garbage"), DetectCompression()), &samples)
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+}
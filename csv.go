@@ -8,6 +8,7 @@ package gocsv
 
 import (
 	"bytes"
+	"context"
 	"encoding/csv"
 	"fmt"
 	"io"
@@ -39,6 +40,7 @@ var selfCSVWriter = DefaultCSVWriter
 // DefaultCSVWriter is the default SafeCSVWriter used to format CSV (cf. csv.NewWriter)
 func DefaultCSVWriter(out io.Writer) *SafeCSVWriter {
 	writer := NewSafeCSVWriter(csv.NewWriter(out))
+	writer.Output = out // To honor the quote tags
 
 	// As only one rune can be defined as a CSV separator, we are going to trim
 	// the custom tag separator and use the first rune.
@@ -123,11 +125,70 @@ func MarshalWithoutHeaders(in interface{}, out io.Writer) (err error) {
 	return writeTo(writer, in, true)
 }
 
+// MarshalWithOptions returns the CSV in writer from the interface, as configured by opts.
+func MarshalWithOptions(in interface{}, out io.Writer, opts ...EncoderOption) (err error) {
+	o := newEncoderOptions(opts)
+	if err := o.check(false, false); err != nil {
+		return err
+	}
+	w, done, err := o.output(out)
+	if err != nil {
+		return err
+	}
+	if err := writeToWithOptions(getCSVWriter(w), in, o); err != nil {
+		return err
+	}
+	return done()
+}
+
+// MarshalPath saves the interface as CSV in the file at path, compressed
+// as told by its extension (.gz, .zz) unless opts give a compression.
+func MarshalPath(in interface{}, path string, opts ...EncoderOption) (err error) {
+	if c, ok := compressionOf(path); ok {
+		opts = append([]EncoderOption{Compress(c)}, opts...)
+	}
+	o := newEncoderOptions(opts)
+	if o.compression == Bzip2 {
+		return errBzip2Writer
+	}
+	if err := o.check(false, false); err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}()
+	return MarshalWithOptions(in, file, opts...)
+}
+
 // MarshalChan returns the CSV read from the channel.
 func MarshalChan(c <-chan interface{}, out *SafeCSVWriter) error {
 	return writeFromChan(out, c)
 }
 
+// MarshalChanContext returns the CSV read from the channel.
+// It stops waiting for values and returns ctx.Err() as soon as ctx is done.
+func MarshalChanContext(ctx context.Context, c <-chan interface{}, out *SafeCSVWriter) error {
+	return writeFromChanContext(ctx, out, c)
+}
+
+// MarshalFromChan writes the values received on the channel as CSV, until it is closed
+// or ctx is done. T must be a struct, a pointer to a struct or an interface type.
+// Unless T is an interface type, the header is written even when no value is received.
+// Every value must have the same struct type, nil pointers are rejected.
+func MarshalFromChan[T any](ctx context.Context, c <-chan T, out *SafeCSVWriter, opts ...EncoderOption) error {
+	o := newEncoderOptions(opts)
+	if err := o.check(false, true); err != nil {
+		return err
+	}
+	return writeFromTypedChan(ctx, out, c, o)
+}
+
 // MarshalCSV returns the CSV in writer from the interface.
 func MarshalCSV(in interface{}, out *SafeCSVWriter) (err error) {
 	return writeTo(out, in, false)
@@ -146,6 +207,23 @@ func UnmarshalFile(in *os.File, out interface{}) error {
 	return Unmarshal(in, out)
 }
 
+// UnmarshalPath parses the CSV from the file at path in the interface. The
+// file is decompressed as told by its extension (.gz, .zz, .bz2) or else by
+// its magic bytes, unless opts give a Decompress.
+func UnmarshalPath(path string, out interface{}, opts ...DecoderOption) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if c, ok := compressionOf(path); ok {
+		opts = append([]DecoderOption{Decompress(c)}, opts...)
+	} else {
+		opts = append([]DecoderOption{DetectCompression()}, opts...)
+	}
+	return UnmarshalDecoder(NewDecoder(file, opts...), out)
+}
+
 // UnmarshalString parses the CSV from the string in the interface.
 func UnmarshalString(in string, out interface{}) error {
 	return Unmarshal(strings.NewReader(in), out)
@@ -161,6 +239,12 @@ func Unmarshal(in io.Reader, out interface{}) error {
 	return readTo(newDecoder(in), out)
 }
 
+// UnmarshalContext parses the CSV from the reader in the interface.
+// ctx is checked between rows; parsing stops and ctx.Err() is returned once it is done.
+func UnmarshalContext(ctx context.Context, in io.Reader, out interface{}) error {
+	return readToContext(ctx, contextDecoder{ctx, newDecoder(in)}, out)
+}
+
 // UnmarshalDecoder parses the CSV from the decoder in the interface
 func UnmarshalDecoder(in Decoder, out interface{}) error {
 	return readTo(in, out)
@@ -180,6 +264,16 @@ func UnmarshalToChan(in io.Reader, c interface{}) error {
 	return readEach(newDecoder(in), c)
 }
 
+// UnmarshalToChanContext parses the CSV from the reader and send each value in the chan c.
+// The channel must have a concrete type.
+// ctx is checked between rows, and a blocked send is abandoned once ctx is done.
+func UnmarshalToChanContext(ctx context.Context, in io.Reader, c interface{}) error {
+	if c == nil {
+		return fmt.Errorf("goscv: channel is %v", c)
+	}
+	return readEachContext(ctx, newDecoder(in), c)
+}
+
 // UnmarshalDecoderToChan parses the CSV from the decoder and send each value in the chan c.
 // The channel must have a concrete type.
 func UnmarshalDecoderToChan(in SimpleDecoder, c interface{}) error {
@@ -269,6 +363,23 @@ func UnmarshalStringToCallback(in string, c interface{}) (err error) {
 	return UnmarshalToCallback(strings.NewReader(in), c)
 }
 
+// UnmarshalBatches parses the CSV from the reader and send the values to the given func f,
+// size values at a time (the last batch may be smaller).
+// T must be a struct or a pointer to a struct.
+// An error returned by f stops the parsing and is returned as a *BatchError.
+// Use ReuseBatch to get the same backing slice in every call.
+func UnmarshalBatches[T any](in io.Reader, size int, f func([]T) error, opts ...BatchOption) error {
+	return UnmarshalBatchesLines(in, size, func(batch []T, _ LineRange) error {
+		return f(batch)
+	}, opts...)
+}
+
+// UnmarshalBatchesLines is like UnmarshalBatches, but also gives f the range of
+// CSV lines the batch was decoded from.
+func UnmarshalBatchesLines[T any](in io.Reader, size int, f func([]T, LineRange) error, opts ...BatchOption) error {
+	return readBatches(newDecoder(in), size, f, opts...)
+}
+
 // CSVToMap creates a simple map from a CSV of 2 columns.
 func CSVToMap(in io.Reader) (map[string]string, error) {
 	decoder := newDecoder(in)
@@ -307,6 +418,7 @@ func CSVToMaps(reader io.Reader) ([]map[string]string, error) {
 		}
 		if header == nil {
 			header = record
+			trimHeaderBOM(header)
 		} else {
 			dict := map[string]string{}
 			for i := range header {
@@ -1,11 +1,14 @@
 package gocsv
 
 import (
+	"bufio"
+	"context"
 	"encoding/csv"
 	"errors"
 	"fmt"
 	"io"
 	"reflect"
+	"strings"
 )
 
 var (
@@ -20,6 +23,45 @@ func (e MissingColumnsError) Error() string {
 	return fmt.Sprintf("unable to find these columns: %v", e.MissingColumnNames)
 }
 
+// LineRange is a range of CSV lines, from the line where its first row starts
+// to the line where its last row starts. The header is line 1. When the
+// CSVReader does not report the positions of its records, as the csv.Reader
+// does with FieldPos, they are record numbers.
+type LineRange struct {
+	First int
+	Last  int
+}
+
+// BatchError is returned by UnmarshalBatches when the batch func fails.
+type BatchError struct {
+	Lines LineRange
+	Err   error
+}
+
+func (e *BatchError) Error() string {
+	return fmt.Sprintf("batch of lines %d to %d: %v", e.Lines.First, e.Lines.Last, e.Err)
+}
+
+func (e *BatchError) Unwrap() error {
+	return e.Err
+}
+
+// BatchOption configures UnmarshalBatches.
+type BatchOption func(*batchOptions)
+
+type batchOptions struct {
+	reuse bool
+}
+
+// ReuseBatch makes UnmarshalBatches pass the same backing slice to every call of
+// the batch func, instead of allocating a new one for each batch. The func must
+// not retain the slice after it returns.
+func ReuseBatch() BatchOption {
+	return func(o *batchOptions) {
+		o.reuse = true
+	}
+}
+
 // Decoder .
 type Decoder interface {
 	getCSVRows() ([][]string, error)
@@ -30,8 +72,73 @@ type SimpleDecoder interface {
 	getCSVRow() ([]string, error)
 }
 
+// ReaderDecoder is a Decoder and a SimpleDecoder of the CSV from an io.Reader.
+type ReaderDecoder interface {
+	Decoder
+	SimpleDecoder
+}
+
+// DecoderOption configures the decoders made by NewDecoder.
+type DecoderOption func(*decoderOptions)
+
+type decoderOptions struct {
+	sniff          bool
+	sniffBytes     int
+	encoding       Encoding
+	encodingSet    bool
+	strictEncoding bool
+	compression    Compression
+	detectCompress bool
+	filter         func([]string, Header) bool
+	whereType      reflect.Type
+	where          func(interface{}) bool
+}
+
+// DetectCompression makes the decoder decompress its input if it starts with
+// the magic bytes of gzip or bzip2. Zlib has no magic bytes that plain text
+// cannot start with, it is only decompressed when told by Decompress.
+func DetectCompression() DecoderOption {
+	return func(o *decoderOptions) {
+		o.detectCompress = true
+	}
+}
+
+// Decompress makes the decoder decompress its input with c, instead of the
+// compression detected by DetectCompression. Decompress(NoCompression) reads
+// the input as it is.
+func Decompress(c Compression) DecoderOption {
+	return func(o *decoderOptions) {
+		o.compression = c
+		o.detectCompress = false
+	}
+}
+
+// InputEncoding makes the decoder read its input in the encoding enc, a byte
+// order mark taking precedence. Invalid bytes are replaced with U+FFFD, or
+// fail the decoding with an *EncodingError when strict. Without it, the input
+// is UTF-8 unless it starts with a UTF-16 byte order mark.
+func InputEncoding(enc Encoding, strict bool) DecoderOption {
+	return func(o *decoderOptions) {
+		o.encoding = enc
+		o.encodingSet = true
+		o.strictEncoding = strict
+	}
+}
+
+// SniffDialect makes the decoder Sniff the first sampleBytes of its input,
+// and read the whole input, sample included, in the sniffed Dialect instead
+// of with the CSVReader set by SetCSVReader, and in the sniffed Encoding
+// unless InputEncoding is given. The first record is still read as the header.
+func SniffDialect(sampleBytes int) DecoderOption {
+	return func(o *decoderOptions) {
+		o.sniff = true
+		o.sniffBytes = sampleBytes
+	}
+}
+
 type decoder struct {
 	in         io.Reader
+	opts       decoderOptions
 	csvDecoder *csvDecoder
 }
 
@@ -39,15 +146,84 @@ func newDecoder(in io.Reader) *decoder {
 	return &decoder{in: in}
 }
 
+// NewDecoder returns a decoder of the CSV from in, configured by opts, for
+// UnmarshalDecoder, UnmarshalDecoderToChan and UnmarshalDecoderToCallback.
+func NewDecoder(in io.Reader, opts ...DecoderOption) ReaderDecoder {
+	decode := newDecoder(in)
+	for _, opt := range opts {
+		opt(&decode.opts)
+	}
+	return decode
+}
+
 func (decode *decoder) getCSVRows() ([][]string, error) {
-	return getCSVReader(decode.in).ReadAll()
+	return decode.getCSVDecoder().ReadAll()
 }
 
 func (decode *decoder) getCSVRow() ([]string, error) {
+	return decode.getCSVDecoder().Read()
+}
+
+func (decode *decoder) getCSVDecoder() *csvDecoder {
 	if decode.csvDecoder == nil {
-		decode.csvDecoder = &csvDecoder{getCSVReader(decode.in)}
+		decode.csvDecoder = &csvDecoder{decode.newCSVReader()}
 	}
-	return decode.csvDecoder.Read()
+	return decode.csvDecoder
+}
+
+// newCSVReader returns the CSVReader of the input, as configured by the options.
+func (decode *decoder) newCSVReader() CSVReader {
+	size := 4096
+	if decode.opts.sniff {
+		size = decode.opts.sniffBytes
+		if size <= 0 {
+			size = defaultSniffBytes
+		}
+	}
+	in := bufio.NewReaderSize(decompress(decode.in, decode.opts.compression, decode.opts.detectCompress), size)
+	enc, validate := decode.opts.encoding, decode.opts.encodingSet
+	var d *Dialect
+	if decode.opts.sniff {
+		sample, err := in.Peek(size)
+		// Empty or unreadable input is reported by the reader.
+		if sniffed, err := sniff(sample, err == nil); err == nil {
+			d = &sniffed
+			if !validate && sniffed.Encoding != UTF8 && sniffed.Encoding != UTF8BOM {
+				enc, validate = sniffed.Encoding, true
+			}
+		}
+	}
+	text := decodeInput(in, enc, validate, decode.opts.strictEncoding)
+	if d != nil {
+		return NewDialectReader(text, *d)
+	}
+	return getCSVReader(text)
+}
+
+// nativeReader returns the Reader the decoder reads from, or nil if it reads
+// from another CSVReader.
+func nativeReader(d interface{}) *Reader {
+	r, _ := csvReaderOf(d).(*Reader)
+	return r
+}
+
+// csvReaderOf returns the CSVReader of the decoder d, nil if unknown.
+func csvReaderOf(d interface{}) CSVReader {
+	switch d := d.(type) {
+	case *decoder:
+		return d.getCSVDecoder().CSVReader
+	case csvDecoder:
+		return d.CSVReader
+	case contextDecoder:
+		return csvReaderOf(d.SimpleDecoder)
+	}
+	return nil
+}
+
+// fieldPositioner is a CSVReader reporting the position of its fields, as
+// the csv.Reader and the Reader do.
+type fieldPositioner interface {
+	FieldPos(field int) (line, column int)
 }
 
 type CSVReader interface {
@@ -67,6 +243,34 @@ func (c csvDecoder) getCSVRow() ([]string, error) {
 	return c.Read()
 }
 
+// contextDecoder reads rows one at a time from a SimpleDecoder and stops
+// with the context error as soon as ctx is done.
+type contextDecoder struct {
+	ctx context.Context
+	SimpleDecoder
+}
+
+func (c contextDecoder) getCSVRows() ([][]string, error) {
+	var rows [][]string
+	for {
+		row, err := c.getCSVRow()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func (c contextDecoder) getCSVRow() ([]string, error) {
+	if err := c.ctx.Err(); err != nil {
+		return nil, err
+	}
+	return c.SimpleDecoder.getCSVRow()
+}
+
 func mismatchStructFields(structInfo []fieldInfo, headers []string) []string {
 	missing := make([]string, 0)
 	if len(structInfo) == 0 {
@@ -122,6 +326,14 @@ func maybeMissingStructFields(structInfo []fieldInfo, headers []string) error {
 	return nil
 }
 
+// trimHeaderBOM drops the UTF-8 byte order mark a CSVReader may have left at
+// the start of the first header.
+func trimHeaderBOM(headers []string) {
+	if len(headers) > 0 {
+		headers[0] = strings.TrimPrefix(headers[0], "\uFEFF")
+	}
+}
+
 // Check that no header name is repeated twice
 func maybeDoubleHeaderNames(headers []string) error {
 	headerMap := make(map[string]bool, len(headers))
@@ -135,6 +347,10 @@ func maybeDoubleHeaderNames(headers []string) error {
 }
 
 func readTo(decoder Decoder, out interface{}) error {
+	return readToContext(context.Background(), decoder, out)
+}
+
+func readToContext(ctx context.Context, decoder Decoder, out interface{}) error {
 	outValue, outType := getConcreteReflectValueAndType(out) // Get the concrete type (not pointer) (Slice<?> or Array<?>)
 	if err := ensureOutType(outType); err != nil {
 		return err
@@ -143,6 +359,10 @@ func readTo(decoder Decoder, out interface{}) error {
 	if err := ensureOutInnerType(outInnerType); err != nil {
 		return err
 	}
+	opts := decoderOptionsOf(decoder)
+	if r := nativeReader(decoder); r != nil {
+		return readToFromReader(ctx, r, outValue, outInnerWasPointer, outInnerType, opts)
+	}
 	csvRows, err := decoder.getCSVRows() // Get the CSV csvRows
 	if err != nil {
 		return err
@@ -153,58 +373,79 @@ func readTo(decoder Decoder, out interface{}) error {
 	if err := ensureOutCapacity(&outValue, len(csvRows)); err != nil { // Ensure the container is big enough to hold the CSV content
 		return err
 	}
-	outInnerStructInfo := getStructInfo(outInnerType) // Get the inner struct info to get CSV annotations
-	if len(outInnerStructInfo.Fields) == 0 {
-		return errors.New("no csv struct tags found")
+	rd, err := newRowDecoder(outInnerWasPointer, outInnerType, csvRows[0], opts)
+	if err != nil {
+		return err
 	}
 
-	headers := csvRows[0]
 	body := csvRows[1:]
-
-	csvHeadersLabels := make(map[int]*fieldInfo, len(outInnerStructInfo.Fields)) // Used to store the correspondance header <-> position in CSV
-
-	headerCount := map[string]int{}
-	for i, csvColumnHeader := range headers {
-		curHeaderCount := headerCount[csvColumnHeader]
-		if fieldInfo := getCSVFieldPosition(csvColumnHeader, outInnerStructInfo, curHeaderCount); fieldInfo != nil {
-			csvHeadersLabels[i] = fieldInfo
-			if ShouldAlignDuplicateHeadersWithStructFieldOrder {
-				curHeaderCount++
-				headerCount[csvColumnHeader] = curHeaderCount
-			}
+	kept := 0
+	for i, csvRow := range body {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		outInner, err := rd.decodeRow(csvRow, i)
+		if err == errRowFiltered {
+			continue
+		} else if err != nil {
+			return err
 		}
+		outValue.Index(kept).Set(outInner)
+		kept++
 	}
+	if kept < len(body) && outValue.Kind() == reflect.Slice && outValue.CanSet() {
+		outValue.Set(outValue.Slice(0, kept)) // Rows were filtered out
+	}
+	return nil
+}
 
-	if FailIfUnmatchedStructTags {
-		if err := maybeMissingStructFields(outInnerStructInfo.Fields, headers); err != nil {
+// readToFromReader is readTo for a native Reader: the rows are converted as
+// they are read, instead of being all read as strings first.
+func readToFromReader(ctx context.Context, r *Reader, outValue reflect.Value, outInnerWasPointer bool, outInnerType reflect.Type, opts *decoderOptions) error {
+	headers, err := r.Read()
+	if err == io.EOF {
+		return ErrEmptyCSV
+	} else if err != nil {
+		return err
+	}
+	rd, err := newRowDecoder(outInnerWasPointer, outInnerType, headers, opts)
+	if err != nil {
+		return err
+	}
+	var values []reflect.Value
+	filtered := false
+	for i := 0; ; i++ {
+		if err := ctx.Err(); err != nil {
 			return err
 		}
-	}
-	if FailIfDoubleHeaderNames {
-		if err := maybeDoubleHeaderNames(headers); err != nil {
+		outInner, err := rd.readRow(nil, r, i)
+		if err == io.EOF {
+			break
+		} else if err == errRowFiltered {
+			filtered = true
+			continue
+		} else if err != nil {
 			return err
 		}
+		values = append(values, outInner)
 	}
-
-	for i, csvRow := range body {
-		outInner := createNewOutInner(outInnerWasPointer, outInnerType)
-		for j, csvColumnContent := range csvRow {
-			if fieldInfo, ok := csvHeadersLabels[j]; ok { // Position found accordingly to header name
-				if err := setInnerField(&outInner, outInnerWasPointer, fieldInfo.IndexChain, csvColumnContent, fieldInfo.omitEmpty); err != nil { // Set field of struct
-					return &csv.ParseError{
-						Line:   i + 2, //add 2 to account for the header & 0-indexing of arrays
-						Column: j + 1,
-						Err:    err,
-					}
-				}
-			}
-		}
-		outValue.Index(i).Set(outInner)
+	if err := ensureOutCapacity(&outValue, len(values)+1); err != nil { // Ensure the container is big enough to hold the CSV content
+		return err
+	}
+	for i, v := range values {
+		outValue.Index(i).Set(v)
+	}
+	if filtered && outValue.Kind() == reflect.Slice && outValue.CanSet() {
+		outValue.Set(outValue.Slice(0, len(values))) // Rows were filtered out
 	}
 	return nil
 }
 
 func readEach(decoder SimpleDecoder, c interface{}) error {
+	return readEachContext(context.Background(), decoder, c)
+}
+
+func readEachContext(ctx context.Context, decoder SimpleDecoder, c interface{}) error {
 	headers, err := decoder.getCSVRow()
 	if err != nil {
 		return err
@@ -218,11 +459,133 @@ func readEach(decoder SimpleDecoder, c interface{}) error {
 	if err := ensureOutInnerType(outInnerType); err != nil {
 		return err
 	}
+	rd, err := newRowDecoder(outInnerWasPointer, outInnerType, headers, decoderOptionsOf(decoder))
+	if err != nil {
+		return err
+	}
+	r := nativeReader(decoder)
+	i := 0
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		outInner, err := rd.readRow(decoder, r, i)
+		if err == io.EOF {
+			break
+		} else if err == errRowFiltered {
+			i++
+			continue
+		} else if err != nil {
+			return err
+		}
+		if err := sendContext(ctx, outValue, outInner); err != nil {
+			return err
+		}
+		i++
+	}
+	return nil
+}
+
+func readBatches[T any](decoder SimpleDecoder, size int, f func([]T, LineRange) error, opts ...BatchOption) error {
+	if size <= 0 {
+		return fmt.Errorf("invalid batch size %d", size)
+	}
+	var o batchOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	outInnerType := reflect.TypeOf((*T)(nil)).Elem()
+	outInnerWasPointer := outInnerType.Kind() == reflect.Ptr
+	if outInnerWasPointer {
+		outInnerType = outInnerType.Elem()
+	}
+	if err := ensureOutInnerType(outInnerType); err != nil {
+		return err
+	}
+	headers, err := decoder.getCSVRow()
+	if err == io.EOF {
+		return ErrEmptyCSV
+	} else if err != nil {
+		return err
+	}
+	rd, err := newRowDecoder(outInnerWasPointer, outInnerType, headers, nil)
+	if err != nil {
+		return err
+	}
+
+	pos, _ := csvReaderOf(decoder).(fieldPositioner)
+	batch := make([]T, 0, size)
+	var lines LineRange
+	flush := func() error {
+		if err := f(batch, lines); err != nil {
+			return &BatchError{Lines: lines, Err: err}
+		}
+		if o.reuse {
+			var zero T
+			for j := range batch {
+				batch[j] = zero // Do not keep the previous values alive
+			}
+			batch = batch[:0]
+		} else {
+			batch = make([]T, 0, size)
+		}
+		return nil
+	}
+	r := nativeReader(decoder)
+	i := 0
+	for {
+		outInner, err := rd.readRow(decoder, r, i)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		line := i + 2 // The header is line 1
+		if pos != nil {
+			line, _ = pos.FieldPos(0)
+		}
+		if len(batch) == 0 {
+			lines.First = line
+		}
+		lines.Last = line
+		batch = append(batch, outInner.Interface().(T))
+		i++
+		if len(batch) == size {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	if len(batch) > 0 {
+		return flush()
+	}
+	return nil
+}
+
+// rowDecoder converts the CSV rows following a header into values of the
+// inner type of the output container.
+type rowDecoder struct {
+	outInnerWasPointer bool
+	outInnerType       reflect.Type
+	csvHeadersLabels   map[int]*fieldInfo // Used to store the correspondance header <-> position in CSV
+	hooks              decodeHooks
+	filters            rowFilter
+}
+
+// newRowDecoder maps the CSV headers to the fields of outInnerType, and checks
+// them against FailIfUnmatchedStructTags and FailIfDoubleHeaderNames. The
+// rows are filtered as told by opts, if not nil.
+func newRowDecoder(outInnerWasPointer bool, outInnerType reflect.Type, headers []string, opts *decoderOptions) (*rowDecoder, error) {
+	trimHeaderBOM(headers)
 	outInnerStructInfo := getStructInfo(outInnerType) // Get the inner struct info to get CSV annotations
 	if len(outInnerStructInfo.Fields) == 0 {
-		return errors.New("no csv struct tags found")
+		return nil, errors.New("no csv struct tags found")
+	}
+	if err := outInnerStructInfo.rulesError(); err != nil {
+		return nil, err
 	}
-	csvHeadersLabels := make(map[int]*fieldInfo, len(outInnerStructInfo.Fields)) // Used to store the correspondance header <-> position in CSV
+
+	csvHeadersLabels := make(map[int]*fieldInfo, len(outInnerStructInfo.Fields))
 	headerCount := map[string]int{}
 	for i, csvColumnHeader := range headers {
 		curHeaderCount := headerCount[csvColumnHeader]
@@ -234,38 +597,123 @@ func readEach(decoder SimpleDecoder, c interface{}) error {
 			}
 		}
 	}
-	if err := maybeMissingStructFields(outInnerStructInfo.Fields, headers); err != nil {
-		if FailIfUnmatchedStructTags {
-			return err
+
+	if FailIfUnmatchedStructTags {
+		if err := maybeMissingStructFields(outInnerStructInfo.Fields, headers); err != nil {
+			return nil, err
 		}
 	}
 	if FailIfDoubleHeaderNames {
 		if err := maybeDoubleHeaderNames(headers); err != nil {
-			return err
+			return nil, err
 		}
 	}
-	i := 0
-	for {
-		line, err := decoder.getCSVRow()
-		if err == io.EOF {
-			break
-		} else if err != nil {
-			return err
+	filters, err := newRowFilter(opts, outInnerType, headers)
+	if err != nil {
+		return nil, err
+	}
+	return &rowDecoder{
+		outInnerWasPointer: outInnerWasPointer,
+		outInnerType:       outInnerType,
+		csvHeadersLabels:   csvHeadersLabels,
+		hooks:              getDecodeHooks(outInnerType),
+		filters:            filters,
+	}, nil
+}
+
+// readRow reads the next row and converts it as the i-th row of the CSV body.
+// It reads from r when it is not nil, from decoder otherwise, and returns
+// io.EOF at the end of the CSV, errRowFiltered if the row is filtered out.
+func (rd *rowDecoder) readRow(decoder SimpleDecoder, r *Reader, i int) (reflect.Value, error) {
+	if r == nil {
+		csvRow, err := decoder.getCSVRow()
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return rd.decodeRow(csvRow, i)
+	}
+	csvRow, err := r.ReadBytes()
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	if rd.filters.filter != nil {
+		row := make([]string, len(csvRow))
+		for j, field := range csvRow {
+			row[j] = string(field)
+		}
+		if !rd.filters.keepRow(row) {
+			return reflect.Value{}, errRowFiltered
 		}
-		outInner := createNewOutInner(outInnerWasPointer, outInnerType)
-		for j, csvColumnContent := range line {
-			if fieldInfo, ok := csvHeadersLabels[j]; ok { // Position found accordingly to header name
-				if err := setInnerField(&outInner, outInnerWasPointer, fieldInfo.IndexChain, csvColumnContent, fieldInfo.omitEmpty); err != nil { // Set field of struct
-					return &csv.ParseError{
-						Line:   i + 2, //add 2 to account for the header & 0-indexing of arrays
-						Column: j + 1,
-						Err:    err,
-					}
+	}
+	outInner := createNewOutInner(rd.outInnerWasPointer, rd.outInnerType)
+	oi := outInner
+	if rd.outInnerWasPointer {
+		oi = outInner.Elem()
+	}
+	for j, csvColumnContent := range csvRow {
+		if fieldInfo, ok := rd.csvHeadersLabels[j]; ok { // Position found accordingly to header name
+			err := setFieldBytes(oi.FieldByIndex(fieldInfo.IndexChain), csvColumnContent, fieldInfo.omitEmpty, r, j) // Set field of struct
+			if err == nil && len(fieldInfo.rules) > 0 {
+				err = fieldInfo.checkRules(string(csvColumnContent))
+			}
+			if err != nil {
+				return outInner, &csv.ParseError{
+					Line:   i + 2, //add 2 to account for the header & 0-indexing of arrays
+					Column: j + 1,
+					Err:    err,
 				}
 			}
 		}
-		outValue.Send(outInner)
-		i++
+	}
+	return rd.finish(outInner, i)
+}
+
+// decodeRow converts the i-th row of the CSV body (0 being the row right after the header).
+// It returns errRowFiltered if the row is filtered out.
+func (rd *rowDecoder) decodeRow(csvRow []string, i int) (reflect.Value, error) {
+	if !rd.filters.keepRow(csvRow) {
+		return reflect.Value{}, errRowFiltered
+	}
+	outInner := createNewOutInner(rd.outInnerWasPointer, rd.outInnerType)
+	for j, csvColumnContent := range csvRow {
+		if fieldInfo, ok := rd.csvHeadersLabels[j]; ok { // Position found accordingly to header name
+			err := setInnerField(&outInner, rd.outInnerWasPointer, fieldInfo.IndexChain, csvColumnContent, fieldInfo.omitEmpty) // Set field of struct
+			if err == nil {
+				err = fieldInfo.checkRules(csvColumnContent)
+			}
+			if err != nil {
+				return outInner, &csv.ParseError{
+					Line:   i + 2, //add 2 to account for the header & 0-indexing of arrays
+					Column: j + 1,
+					Err:    err,
+				}
+			}
+		}
+	}
+	return rd.finish(outInner, i)
+}
+
+// finish filters the value converted from the i-th row of the CSV body with
+// Where, then calls its hooks.
+func (rd *rowDecoder) finish(outInner reflect.Value, i int) (reflect.Value, error) {
+	if !rd.filters.keepValue(outInner) {
+		return reflect.Value{}, errRowFiltered
+	}
+	return outInner, rd.hooks.run(outInner, i+2)
+}
+
+// sendContext sends v on the channel c, giving up when ctx is done first.
+func sendContext(ctx context.Context, c reflect.Value, v reflect.Value) error {
+	if ctx.Done() == nil {
+		c.Send(v)
+		return nil
+	}
+	chosen, _, _ := reflect.Select([]reflect.SelectCase{
+		{Dir: reflect.SelectSend, Chan: c, Send: v},
+		{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())},
+	})
+	if chosen == 1 {
+		return ctx.Err()
 	}
 	return nil
 }
@@ -2,7 +2,9 @@ package gocsv
 
 import (
 	"bytes"
+	"context"
 	"encoding/csv"
+	"errors"
 	"io"
 	"reflect"
 	"strconv"
@@ -406,6 +408,117 @@ ff,gg,22,hh,ii,jj`)
 	}
 }
 
+func TestUnmarshalContext(t *testing.T) {
+	b := []byte(`Baz,BAR
+abc,123
+def,234`)
+	var samples []MultiTagSample
+	if err := UnmarshalContext(context.Background(), bytes.NewReader(b), &samples); err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 2 {
+		t.Fatalf("expected 2 sample instances, got %d", len(samples))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	samples = nil
+	if err := UnmarshalContext(ctx, bytes.NewReader(b), &samples); err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestUnmarshalToChanContext(t *testing.T) {
+	b := []byte(`Baz,BAR
+abc,123
+def,234`)
+	ctx, cancel := context.WithCancel(context.Background())
+	c := make(chan MultiTagSample)
+	cerr := make(chan error)
+	go func() {
+		cerr <- UnmarshalToChanContext(ctx, bytes.NewReader(b), c)
+	}()
+	if v := <-c; v.Foo != "abc" {
+		t.Fatalf("expected first sample abc, got %v", v)
+	}
+	// Nobody receives the second value: cancelling must unblock the send.
+	cancel()
+	if err := <-cerr; err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if _, ok := <-c; ok {
+		t.Fatalf("expected channel to be closed")
+	}
+}
+
+func TestUnmarshalBatches(t *testing.T) {
+	b := bytes.NewBufferString(`Baz,BAR
+a,1
+b,2
+c,3
+d,4
+e,5`)
+	var batches [][]MultiTagSample
+	var lines []LineRange
+	if err := UnmarshalBatchesLines(b, 2, func(batch []MultiTagSample, r LineRange) error {
+		batches = append(batches, batch)
+		lines = append(lines, r)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(batches) != 3 || len(batches[0]) != 2 || len(batches[2]) != 1 {
+		t.Fatalf("expected batches of 2, 2 and 1 values, got %v", batches)
+	}
+	if batches[0][0].Foo != "a" || batches[1][1].Foo != "d" || batches[2][0].Foo != "e" {
+		t.Fatalf("unexpected batches %v", batches)
+	}
+	expectedLines := []LineRange{{2, 3}, {4, 5}, {6, 6}}
+	if !reflect.DeepEqual(expectedLines, lines) {
+		t.Fatalf("expected line ranges %v, got %v", expectedLines, lines)
+	}
+
+	b = bytes.NewBufferString(`Baz,BAR
+a,1
+b,2
+c,3`)
+	var backing []**MultiTagSample
+	failure := errors.New("insert failed")
+	err := UnmarshalBatches(b, 2, func(batch []*MultiTagSample) error {
+		backing = append(backing, &batch[:1][0])
+		if batch[0].Foo == "c" {
+			return failure
+		}
+		return nil
+	}, ReuseBatch())
+	if len(backing) != 2 || backing[0] != backing[1] {
+		t.Fatalf("expected the two batches to share their backing array")
+	}
+	berr, ok := err.(*BatchError)
+	if !ok {
+		t.Fatalf("expected *BatchError, got %v", err)
+	}
+	if berr.Lines != (LineRange{4, 4}) || !errors.Is(err, failure) {
+		t.Fatalf("expected failure on lines 4 to 4, got %v", berr)
+	}
+	// The ranges follow the lines of the fields spanning several lines
+	in := "Baz,BAR\n\"a\nb\",1\nc,2\n\"d\n\ne\",3\nf,4\n"
+	for _, reader := range []func(io.Reader) CSVReader{DefaultCSVReader, func(in io.Reader) CSVReader { return NewReader(in) }} {
+		SetCSVReader(reader)
+		lines = lines[:0]
+		if err := UnmarshalBatchesLines(strings.NewReader(in), 2, func(_ []MultiTagSample, r LineRange) error {
+			lines = append(lines, r)
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		if expectedLines := []LineRange{{2, 4}, {5, 8}}; !reflect.DeepEqual(expectedLines, lines) {
+			t.Errorf("expected line ranges %v, got %v", expectedLines, lines)
+		}
+	}
+	SetCSVReader(DefaultCSVReader)
+}
+
 // TestRenamedTypes tests for unmarshaling functions on redefined basic types.
 func TestRenamedTypesUnmarshal(t *testing.T) {
 	b := bytes.NewBufferString(`foo;bar
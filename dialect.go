@@ -0,0 +1,93 @@
+package gocsv
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+)
+
+// Dialect describes a CSV format, for NewDialectReader and NewDialectWriter.
+type Dialect struct {
+	// Delimiter separates the fields of a record. It may be several
+	// characters long. Defaults to ",".
+	Delimiter string
+	// Quote encloses the fields holding special characters. Defaults to '"'.
+	Quote rune
+	// Escape, when not 0, makes the character following it literal, in
+	// quoted and non-quoted fields.
+	Escape rune
+	// DoubleQuote makes two quotes in a quoted field stand for one quote.
+	DoubleQuote bool
+	// RecordTerminator ends each record written: "\n", the default, "\r\n"
+	// or "\r". Records read may end with "\n" or "\r\n", or with "\r" if it
+	// is the RecordTerminator.
+	RecordTerminator string
+
+	// Header and Encoding are only reported by Sniff, readers and writers
+	// ignore them. Header tells whether the first record is a header.
+	Header   bool
+	Encoding Encoding
+}
+
+// DefaultDialect returns the dialect of RFC 4180, read and written by csv.Reader and csv.Writer.
+func DefaultDialect() Dialect {
+	return Dialect{
+		Delimiter:        ",",
+		Quote:            '"',
+		DoubleQuote:      true,
+		RecordTerminator: "\n",
+	}
+}
+
+var errInvalidDialect = errors.New("gocsv: invalid dialect")
+
+// dialect is a validated Dialect, with its separators as bytes.
+type dialect struct {
+	delim       []byte
+	quote       []byte
+	escape      []byte
+	doubleQuote bool
+	terminator  []byte
+	lineEnd     byte
+}
+
+func (d Dialect) compile() (*dialect, error) {
+	if d.Delimiter == "" {
+		d.Delimiter = ","
+	}
+	if d.Quote == 0 {
+		d.Quote = '"'
+	}
+	if d.RecordTerminator == "" {
+		d.RecordTerminator = "\n"
+	}
+	switch d.RecordTerminator {
+	case "\n", "\r\n", "\r": // The line endings the Reader reads back
+	default:
+		return nil, errInvalidDialect
+	}
+	if !validSeparator(d.Quote) || strings.ContainsAny(d.Delimiter, "\r\n") || strings.ContainsRune(d.Delimiter, d.Quote) {
+		return nil, errInvalidDialect
+	}
+	if d.Escape != 0 && (!validSeparator(d.Escape) || d.Escape == d.Quote || strings.ContainsRune(d.Delimiter, d.Escape)) {
+		return nil, errInvalidDialect
+	}
+	c := &dialect{
+		delim:       []byte(d.Delimiter),
+		quote:       utf8.AppendRune(nil, d.Quote),
+		doubleQuote: d.DoubleQuote,
+		terminator:  []byte(d.RecordTerminator),
+		lineEnd:     '\n',
+	}
+	if d.Escape != 0 {
+		c.escape = utf8.AppendRune(nil, d.Escape)
+	}
+	if d.RecordTerminator == "\r" {
+		c.lineEnd = '\r'
+	}
+	return c, nil
+}
+
+func validSeparator(r rune) bool {
+	return r != 0 && r != '\r' && r != '\n' && utf8.ValidRune(r) && r != utf8.RuneError
+}
@@ -0,0 +1,224 @@
+package gocsv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDialectReader(t *testing.T) {
+	tests := []struct {
+		name     string
+		dialect  Dialect
+		input    string
+		expected [][]string
+	}{
+		{"MultiCharDelimiter", Dialect{Delimiter: "||", DoubleQuote: true}, "a||b||c\n\"d||e\"||f||\n", [][]string{{"a", "b", "c"}, {"d||e", "f", ""}}},
+		{"SingleQuote", Dialect{Quote: '\'', DoubleQuote: true}, "'a,b','c''d'\n", [][]string{{"a,b", "c'd"}}},
+		{"BackslashEscape", Dialect{Escape: '\\'}, "\"a\\\"b\",c\\,d,e\\\\f\n", [][]string{{`a"b`, "c,d", `e\f`}}},
+		{"EscapedNewline", Dialect{Escape: '\\'}, "a\\\nb,c\n", [][]string{{"a\nb", "c"}}},
+		{"CRTerminator", Dialect{DoubleQuote: true, RecordTerminator: "\r"}, "a,b\rc,d\r", [][]string{{"a", "b"}, {"c", "d"}}},
+		{"Tab", Dialect{Delimiter: "\t", DoubleQuote: true, RecordTerminator: "\r\n"}, "a\tb\r\nc\td\r\n", [][]string{{"a", "b"}, {"c", "d"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			records, err := NewDialectReader(strings.NewReader(tt.input), tt.dialect).ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(tt.expected, records) {
+				t.Fatalf("expected %q, got %q", tt.expected, records)
+			}
+
+			// Writing the records back must give records reading the same.
+			b := bytes.Buffer{}
+			if err := NewDialectWriter(&b, tt.dialect).WriteAll(records); err != nil {
+				t.Fatal(err)
+			}
+			again, err := NewDialectReader(&b, tt.dialect).ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(records, again) {
+				t.Fatalf("expected %q after writing %q, got %q", records, b.String(), again)
+			}
+		})
+	}
+
+	if _, err := NewDialectReader(strings.NewReader("a"), Dialect{Delimiter: "\n"}).Read(); err != errInvalidDialect {
+		t.Fatalf("expected errInvalidDialect, got %v", err)
+	}
+}
+
+func TestDialectWriter(t *testing.T) {
+	b := bytes.Buffer{}
+	w := NewDialectWriter(&b, Dialect{Delimiter: "||", Quote: '\'', Escape: '\\', RecordTerminator: "\r\n"})
+	if err := w.WriteAll([][]string{{"a", "b||c", "it's", `back\slash`, " lead"}}); err != nil {
+		t.Fatal(err)
+	}
+	expected := `a||'b||c'||'it\'s'||'back\\slash'||' lead'` + "\r\n"
+	if b.String() != expected {
+		t.Fatalf("expected %q, got %q", expected, b.String())
+	}
+
+	w = NewDialectWriter(io.Discard, Dialect{})
+	if err := w.Write([]string{`"`}); err != errNoQuoteEscape {
+		t.Fatalf("expected errNoQuoteEscape, got %v", err)
+	}
+	// Only the line endings the Reader reads back can end the records
+	for _, terminator := range []string{";", "\n\n", "|\n"} {
+		w = NewDialectWriter(io.Discard, Dialect{RecordTerminator: terminator})
+		if err := w.Write([]string{"a"}); err != errInvalidDialect {
+			t.Errorf("%q: expected errInvalidDialect, got %v", terminator, err)
+		}
+	}
+	for _, terminator := range []string{"\n", "\r\n", "\r"} {
+		d := Dialect{DoubleQuote: true, RecordTerminator: terminator}
+		records := [][]string{{"a\rb", "c\nd", "e"}, {"f\r", "", "g"}}
+		b.Reset()
+		if err := NewDialectWriter(&b, d).WriteAll(records); err != nil {
+			t.Fatal(err)
+		}
+		again, err := NewDialectReader(&b, d).ReadAll()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(records, again) {
+			t.Errorf("%q: expected %q, got %q", terminator, records, again)
+		}
+	}
+}
+
+func TestMarshal_dialectWriter(t *testing.T) {
+	b := bytes.Buffer{}
+	w := NewSafeWriter(NewDialectWriter(&b, Dialect{Delimiter: "||", DoubleQuote: true}))
+	if err := MarshalCSV([]MultiTagSample{{Foo: "a||b", Bar: 1}}, w); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "Baz||BAR\n\"a||b\"||1\n" {
+		t.Fatalf("unexpected output %q", b.String())
+	}
+	var samples []MultiTagSample
+	if err := UnmarshalCSV(NewDialectReader(&b, Dialect{Delimiter: "||", DoubleQuote: true}), &samples); err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 1 || samples[0].Foo != "a||b" {
+		t.Fatalf("unexpected samples %v", samples)
+	}
+}
+
+func TestWriter_Quoting(t *testing.T) {
+	tests := []struct {
+		quoting QuotePolicy
+		out     string
+	}{
+		{QuoteMinimal, "a,12,,-1.5e3,\"b,c\"\n"},
+		{QuoteAll, "\"a\",\"12\",\"\",\"-1.5e3\",\"b,c\"\n"},
+		{QuoteNonNumeric, "\"a\",12,\"\",-1.5e3,\"b,c\"\n"},
+	}
+	for _, test := range tests {
+		b := bytes.Buffer{}
+		w := NewWriter(&b)
+		w.Quoting = test.quoting
+		if err := w.WriteAll([][]string{{"a", "12", "", "-1.5e3", "b,c"}}); err != nil {
+			t.Fatal(err)
+		}
+		if b.String() != test.out {
+			t.Errorf("policy %d: expected %q, got %q", test.quoting, test.out, b.String())
+		}
+	}
+}
+
+type quoteTagSample struct {
+	ID   int    `csv:"id"`
+	Name string `csv:"name" quote:"always"`
+}
+
+func TestMarshal_quoteTag(t *testing.T) {
+	b := bytes.Buffer{}
+	w := NewSafeWriter(NewWriter(&b))
+	if err := MarshalCSV([]quoteTagSample{{1, "a"}, {2, ""}}, w); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "id,\"name\"\n1,\"a\"\n2,\"\"\n"; b.String() != expected {
+		t.Fatalf("expected %q, got %q", expected, b.String())
+	}
+	// The tags no longer apply once the encoding is over.
+	if err := w.Write([]string{"3", "b"}); err != nil {
+		t.Fatal(err)
+	}
+	w.Flush()
+	if !strings.HasSuffix(b.String(), "\n3,b\n") {
+		t.Fatalf("unexpected output %q", b.String())
+	}
+}
+
+func TestNewSafeWriter_csvWriterMethods(t *testing.T) {
+	b := bytes.Buffer{}
+	w := NewSafeWriter(NewDialectWriter(&b, Dialect{Delimiter: ";", DoubleQuote: true}))
+	w.Comma = '|' // Ignored
+	if err := w.WriteAll([][]string{{"a", "b"}, {"c", "d"}}); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "a;b\nc;d\n"; b.String() != expected {
+		t.Fatalf("expected %q, got %q", expected, b.String())
+	}
+}
+
+func TestMarshal_quoteTagDefaultWriter(t *testing.T) {
+	out, err := MarshalString([]quoteTagSample{{1, "a"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "id,\"name\"\n1,\"a\"\n"; out != expected {
+		t.Fatalf("expected %q, got %q", expected, out)
+	}
+
+	// Without an Output, a csv.Writer ignores the tags
+	b := bytes.Buffer{}
+	w := NewSafeCSVWriter(csv.NewWriter(&b))
+	if err := MarshalCSV([]quoteTagSample{{1, "a"}}, w); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "id,name\n1,a\n"; b.String() != expected {
+		t.Fatalf("expected %q, got %q", expected, b.String())
+	}
+
+	// With one, the rows before and after are written in order
+	b.Reset()
+	w = NewSafeCSVWriter(csv.NewWriter(&b))
+	w.Output, w.Comma = &b, ';'
+	if err := w.Write([]string{"x", "y"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := MarshalCSVWithoutHeaders([]quoteTagSample{{1, "a"}}, w); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteAll([][]string{{"z", "w"}}); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "x;y\n1;\"a\"\nz;w\n"; b.String() != expected {
+		t.Fatalf("expected %q, got %q", expected, b.String())
+	}
+}
+
+type nonNumericSample struct {
+	Code  string  `csv:"code"`
+	Count int     `csv:"count"`
+	Price float64 `csv:"price"`
+}
+
+func TestMarshal_quoteNonNumericKinds(t *testing.T) {
+	b := bytes.Buffer{}
+	qw := NewWriter(&b)
+	qw.Quoting = QuoteNonNumeric
+	if err := MarshalCSVWithoutHeaders([]nonNumericSample{{"01234", 3, 1.5}}, NewSafeWriter(qw)); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "\"01234\",3,1.5\n"; b.String() != expected {
+		t.Fatalf("expected %q, got %q", expected, b.String())
+	}
+}
@@ -0,0 +1,440 @@
+package gocsv
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// DiffOp is the kind of difference of a row between two CSVs, as written in
+// the _op column of a changeset.
+type DiffOp string
+
+const (
+	// RowAdded is a row of the second CSV only.
+	RowAdded DiffOp = "added"
+	// RowRemoved is a row of the first CSV only.
+	RowRemoved DiffOp = "removed"
+	// RowChanged is a row of both CSVs, with other values in some columns.
+	RowChanged DiffOp = "changed"
+)
+
+// ColumnChange is a column of which the value of a row changed.
+type ColumnChange struct {
+	Column string
+	Old    string
+	New    string
+}
+
+// RowDiff is a row that differs between two CSVs, identified by the values
+// of its key columns.
+type RowDiff struct {
+	Op  DiffOp
+	Key []string
+	// LineA and LineB are the lines where the row starts in each CSV, 0 if
+	// it is not in it. When the CSVReader does not report the positions of
+	// its records, as the csv.Reader does with FieldPos, they are record
+	// numbers, 1 being the header.
+	LineA, LineB int
+	// Row is the row in the second CSV, or in the first one if removed, in
+	// the order of the columns of the diff.
+	Row []string
+	// Changes are the columns of a changed row that differ, in order.
+	Changes []ColumnChange
+}
+
+// DiffResult is the difference between two CSVs.
+type DiffResult struct {
+	// Columns are the columns of the first CSV, then the columns of the
+	// second CSV only.
+	Columns []string
+	Rows    []RowDiff
+}
+
+// DiffOption configures Diff and DiffEach.
+type DiffOption func(*diffOptions)
+
+type diffOptions struct {
+	sorted bool
+}
+
+// SortedInputs makes Diff and DiffEach merge the two CSVs as they read them,
+// both being sorted by key, instead of keeping the first in memory. The keys
+// are compared column by column as strings. An error is returned as soon as
+// a key is not greater than the one before it.
+func SortedInputs() DiffOption {
+	return func(o *diffOptions) {
+		o.sorted = true
+	}
+}
+
+// Diff compares the CSVs read from a and b, matching their rows by the values
+// of the keyColumns, which must be in both and unique in each. A column in
+// one CSV only is empty in the other one.
+func Diff(a, b io.Reader, keyColumns []string, opts ...DiffOption) (*DiffResult, error) {
+	result := &DiffResult{}
+	err := diffEach(a, b, keyColumns, func(d *differ) {
+		result.Columns = d.columns
+	}, func(_ []string, d RowDiff) error {
+		result.Rows = append(result.Rows, d)
+		return nil
+	}, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// DiffEach is Diff calling f with the columns of the diff and each row that
+// differs instead of returning them, in the order of the rows of b then the
+// removed rows in the order of a, or in the order of the keys with
+// SortedInputs. It stops at the first error of f, and returns it.
+func DiffEach(a, b io.Reader, keyColumns []string, f func(columns []string, d RowDiff) error, opts ...DiffOption) error {
+	return diffEach(a, b, keyColumns, nil, f, opts...)
+}
+
+// diffEach is DiffEach calling start, when not nil, with the differ before
+// the first row.
+func diffEach(a, b io.Reader, keyColumns []string, start func(*differ), f func([]string, RowDiff) error, opts ...DiffOption) error {
+	var o diffOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if len(keyColumns) == 0 {
+		return fmt.Errorf("no key columns")
+	}
+	inA, err := newDiffInput(a, keyColumns)
+	if err != nil {
+		return err
+	}
+	inB, err := newDiffInput(b, keyColumns)
+	if err != nil {
+		return err
+	}
+	d := newDiffer(inA, inB, keyColumns, f)
+	if start != nil {
+		start(d)
+	}
+	if o.sorted {
+		return d.merge()
+	}
+	return d.join()
+}
+
+// diffInput reads the rows of a CSV to compare.
+type diffInput struct {
+	reader  CSVReader
+	headers []string
+	keys    []int           // Positions of the key columns
+	line    int             // Line of the last row read
+	pos     fieldPositioner // The reader, if it reports the lines of its rows
+}
+
+// diffRow is a row of a diffInput.
+type diffRow struct {
+	line   int
+	key    []string
+	values []string
+}
+
+func newDiffInput(in io.Reader, keyColumns []string) (*diffInput, error) {
+	reader := getCSVReader(in)
+	headers, err := reader.Read()
+	if err == io.EOF {
+		return nil, ErrEmptyCSV
+	} else if err != nil {
+		return nil, err
+	}
+	headers = append([]string(nil), headers...) // The reader can reuse its records
+	trimHeaderBOM(headers)
+	if err := maybeDoubleHeaderNames(headers); err != nil {
+		return nil, err
+	}
+	keys, err := mappingIndexes(headers, keyColumns)
+	if err != nil {
+		return nil, err
+	}
+	input := &diffInput{reader: reader, headers: headers, keys: keys, line: 1}
+	input.pos, _ = reader.(fieldPositioner)
+	return input, nil
+}
+
+// next returns the next row, or nil at the end of the CSV.
+func (in *diffInput) next() (*diffRow, error) {
+	record, err := in.reader.Read()
+	if err == io.EOF {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if in.pos != nil {
+		in.line, _ = in.pos.FieldPos(0)
+	} else {
+		in.line++
+	}
+	row := &diffRow{line: in.line, key: make([]string, len(in.keys)), values: append([]string(nil), record...)}
+	for i, index := range in.keys {
+		if index >= len(record) {
+			return nil, fmt.Errorf("line %d: missing key column %s", in.line, in.headers[index])
+		}
+		row.key[i] = record[index]
+	}
+	return row, nil
+}
+
+// differ compares the rows of two diffInputs.
+type differ struct {
+	a, b    *diffInput
+	columns []string
+	fromA   []int // Position of each column in a, -1 if not in a
+	fromB   []int // Position of each column in b, -1 if not in b
+	isKey   []bool
+	emit    func(columns []string, d RowDiff) error
+}
+
+func newDiffer(a, b *diffInput, keyColumns []string, emit func([]string, RowDiff) error) *differ {
+	d := &differ{a: a, b: b, emit: emit}
+	inA := make(map[string]bool, len(a.headers))
+	for i, h := range a.headers {
+		inA[h] = true
+		d.columns = append(d.columns, h)
+		d.fromA = append(d.fromA, i)
+	}
+	for _, h := range b.headers {
+		if !inA[h] {
+			d.columns = append(d.columns, h)
+			d.fromA = append(d.fromA, -1)
+		}
+	}
+	positionsB := make(map[string]int, len(b.headers))
+	for i, h := range b.headers {
+		positionsB[h] = i
+	}
+	keys := make(map[string]bool, len(keyColumns))
+	for _, k := range keyColumns {
+		keys[k] = true
+	}
+	for _, c := range d.columns {
+		index, ok := positionsB[c]
+		if !ok {
+			index = -1
+		}
+		d.fromB = append(d.fromB, index)
+		d.isKey = append(d.isKey, keys[c])
+	}
+	return d
+}
+
+// values returns the values of row, in the order of the columns of the diff.
+func (d *differ) values(row *diffRow, from []int) []string {
+	values := make([]string, len(from))
+	for i, index := range from {
+		if index >= 0 && index < len(row.values) {
+			values[i] = row.values[index]
+		}
+	}
+	return values
+}
+
+func (d *differ) added(row *diffRow) error {
+	return d.emit(d.columns, RowDiff{Op: RowAdded, Key: row.key, LineB: row.line, Row: d.values(row, d.fromB)})
+}
+
+func (d *differ) removed(row *diffRow) error {
+	return d.emit(d.columns, RowDiff{Op: RowRemoved, Key: row.key, LineA: row.line, Row: d.values(row, d.fromA)})
+}
+
+// compare emits the row of a and b if they differ.
+func (d *differ) compare(rowA, rowB *diffRow) error {
+	old, values := d.values(rowA, d.fromA), d.values(rowB, d.fromB)
+	var changes []ColumnChange
+	for i, c := range d.columns {
+		if !d.isKey[i] && old[i] != values[i] {
+			changes = append(changes, ColumnChange{Column: c, Old: old[i], New: values[i]})
+		}
+	}
+	if changes == nil {
+		return nil
+	}
+	return d.emit(d.columns, RowDiff{Op: RowChanged, Key: rowB.key, LineA: rowA.line, LineB: rowB.line, Row: values, Changes: changes})
+}
+
+// join keeps the rows of a in memory, by key, and looks up each row of b.
+func (d *differ) join() error {
+	rowsA := make(map[string]*diffRow)
+	var order []*diffRow
+	for {
+		row, err := d.a.next()
+		if err != nil {
+			return err
+		} else if row == nil {
+			break
+		}
+		k := joinKey(row.key)
+		if other, ok := rowsA[k]; ok {
+			return duplicateKeyError(row, other)
+		}
+		rowsA[k] = row
+		order = append(order, row)
+	}
+	seenB := make(map[string]int)
+	for {
+		row, err := d.b.next()
+		if err != nil {
+			return err
+		} else if row == nil {
+			break
+		}
+		k := joinKey(row.key)
+		if line, ok := seenB[k]; ok {
+			return duplicateKeyError(row, &diffRow{line: line})
+		}
+		seenB[k] = row.line
+		rowA, ok := rowsA[k]
+		if !ok {
+			err = d.added(row)
+		} else {
+			delete(rowsA, k)
+			err = d.compare(rowA, row)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for _, row := range order {
+		if _, ok := rowsA[joinKey(row.key)]; ok {
+			if err := d.removed(row); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// merge reads a and b side by side, both sorted by key.
+func (d *differ) merge() error {
+	var prevA, prevB *diffRow
+	next := func(in *diffInput, prev **diffRow) (*diffRow, error) {
+		row, err := in.next()
+		if err != nil || row == nil {
+			return nil, err
+		}
+		if *prev != nil {
+			switch c := compareKeys((*prev).key, row.key); {
+			case c == 0:
+				return nil, duplicateKeyError(row, *prev)
+			case c > 0:
+				return nil, fmt.Errorf("line %d: key %s is not sorted, it comes before the key of line %d",
+					row.line, formatKey(row.key), (*prev).line)
+			}
+		}
+		*prev = row
+		return row, nil
+	}
+	rowA, err := next(d.a, &prevA)
+	if err != nil {
+		return err
+	}
+	rowB, err := next(d.b, &prevB)
+	if err != nil {
+		return err
+	}
+	for rowA != nil || rowB != nil {
+		switch {
+		case rowB == nil || rowA != nil && compareKeys(rowA.key, rowB.key) < 0:
+			if err := d.removed(rowA); err != nil {
+				return err
+			}
+			rowA, err = next(d.a, &prevA)
+		case rowA == nil || compareKeys(rowA.key, rowB.key) > 0:
+			if err := d.added(rowB); err != nil {
+				return err
+			}
+			rowB, err = next(d.b, &prevB)
+		default:
+			if err := d.compare(rowA, rowB); err != nil {
+				return err
+			}
+			if rowA, err = next(d.a, &prevA); err == nil {
+				rowB, err = next(d.b, &prevB)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// joinKey returns the map key of the key columns, each quoted so that no
+// other columns make the same.
+func joinKey(key []string) string {
+	var b strings.Builder
+	for _, k := range key {
+		b.WriteString(strconv.Quote(k))
+	}
+	return b.String()
+}
+
+func compareKeys(a, b []string) int {
+	for i := range a {
+		if c := strings.Compare(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	return 0
+}
+
+func formatKey(key []string) string {
+	return strings.Join(key, ", ")
+}
+
+func duplicateKeyError(row, other *diffRow) error {
+	return fmt.Errorf("line %d: duplicate key %s, already on line %d", row.line, formatKey(row.key), other.line)
+}
+
+// WriteChangeset writes the rows of the diff as CSV in w: a header of the
+// _op column then the columns of the diff, and a row for each RowDiff with
+// its DiffOp then its Row.
+func (r *DiffResult) WriteChangeset(w io.Writer) error {
+	writer := getCSVWriter(w)
+	record := append([]string{"_op"}, r.Columns...)
+	if err := writer.Write(record); err != nil {
+		return err
+	}
+	for _, d := range r.Rows {
+		record = append(record[:0], string(d.Op))
+		record = append(record, d.Row...)
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteReport writes a report of the diff for humans in w: a line for each
+// RowDiff, followed by the old and new values of the changed columns, and
+// the count of each DiffOp.
+func (r *DiffResult) WriteReport(w io.Writer) error {
+	b := bufio.NewWriter(w)
+	counts := make(map[DiffOp]int)
+	for _, d := range r.Rows {
+		counts[d.Op]++
+		switch d.Op {
+		case RowAdded:
+			fmt.Fprintf(b, "+ %s (line %d)\n", formatKey(d.Key), d.LineB)
+		case RowRemoved:
+			fmt.Fprintf(b, "- %s (line %d)\n", formatKey(d.Key), d.LineA)
+		case RowChanged:
+			fmt.Fprintf(b, "~ %s (lines %d and %d)\n", formatKey(d.Key), d.LineA, d.LineB)
+			for _, c := range d.Changes {
+				fmt.Fprintf(b, "    %s: %q -> %q\n", c.Column, c.Old, c.New)
+			}
+		}
+	}
+	fmt.Fprintf(b, "%d added, %d removed, %d changed\n", counts[RowAdded], counts[RowRemoved], counts[RowChanged])
+	return b.Flush()
+}
@@ -0,0 +1,132 @@
+package gocsv
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const (
+	diffA = "id,region,name,price\n1,EU,apple,10\n2,EU,pear,20\n3,US,plum,30\n"
+	diffB = "id,region,price,name,stock\n1,EU,12,apple,\n3,US,30,plum,\n4,US,40,fig,5\n"
+)
+
+func TestDiff(t *testing.T) {
+	for _, opts := range [][]DiffOption{nil, {SortedInputs()}} {
+		result, err := Diff(strings.NewReader(diffA), strings.NewReader(diffB), []string{"id", "region"}, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := []string{"id", "region", "name", "price", "stock"}; !reflect.DeepEqual(result.Columns, expected) {
+			t.Errorf("got columns %v, wanted %v", result.Columns, expected)
+		}
+		expected := []RowDiff{
+			{Op: RowChanged, Key: []string{"1", "EU"}, LineA: 2, LineB: 2, Row: []string{"1", "EU", "apple", "12", ""},
+				Changes: []ColumnChange{{Column: "price", Old: "10", New: "12"}}},
+			{Op: RowRemoved, Key: []string{"2", "EU"}, LineA: 3, Row: []string{"2", "EU", "pear", "20", ""}},
+			{Op: RowAdded, Key: []string{"4", "US"}, LineB: 4, Row: []string{"4", "US", "fig", "40", "5"}},
+		}
+		if len(opts) == 0 { // The removed rows come last
+			expected = []RowDiff{expected[0], expected[2], expected[1]}
+		}
+		if !reflect.DeepEqual(result.Rows, expected) {
+			t.Errorf("sorted %v: got %+v, wanted %+v", len(opts) > 0, result.Rows, expected)
+		}
+	}
+}
+
+func TestDiff_identical(t *testing.T) {
+	result, err := Diff(strings.NewReader(diffA), strings.NewReader(diffA), []string{"id"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Rows) != 0 {
+		t.Errorf("got rows %+v, wanted none", result.Rows)
+	}
+	var changeset bytes.Buffer
+	if err := result.WriteChangeset(&changeset); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "_op,id,region,name,price\n"; changeset.String() != expected {
+		t.Errorf("got changeset %q, wanted %q", changeset.String(), expected)
+	}
+}
+
+func TestDiff_multilineFields(t *testing.T) {
+	a := "id,note\n1,\"two\nlines\"\n2,x\n"
+	b := "id,note\n1,\"two\nlines\"\n2,y\n"
+	for _, opts := range [][]DiffOption{nil, {SortedInputs()}} {
+		result, err := Diff(strings.NewReader(a), strings.NewReader(b), []string{"id"}, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(result.Rows) != 1 || result.Rows[0].LineA != 4 || result.Rows[0].LineB != 4 {
+			t.Errorf("got %+v, wanted the row of id 2 on line 4", result.Rows)
+		}
+	}
+}
+
+func TestDiff_separatorInKeys(t *testing.T) {
+	a := "k1,k2,v\na\x00b,c,1\na,b\x00c,2\n"
+	b := "k1,k2,v\na,b\x00c,2\na\x00b,c,3\n"
+	result, err := Diff(strings.NewReader(a), strings.NewReader(b), []string{"k1", "k2"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Rows) != 1 || !reflect.DeepEqual(result.Rows[0].Key, []string{"a\x00b", "c"}) || result.Rows[0].Op != RowChanged {
+		t.Errorf("got %+v, wanted the row of key a\\x00b,c changed", result.Rows)
+	}
+}
+
+func TestDiff_errors(t *testing.T) {
+	tests := []struct {
+		name, a, b string
+		keys       []string
+		opts       []DiffOption
+		err        string
+	}{
+		{"missing key", "id\n1\n", "id\n1\n", []string{"sku"}, nil, "unable to find these columns: [sku]"},
+		{"duplicate key", "id\n1\n1\n", "id\n1\n", []string{"id"}, nil, "line 3: duplicate key 1, already on line 2"},
+		{"duplicate key in b", "id\n1\n", "id\n2\n2\n", []string{"id"}, nil, "line 3: duplicate key 2, already on line 2"},
+		{"not sorted", "id\n2\n1\n", "id\n1\n", []string{"id"}, []DiffOption{SortedInputs()}, "line 3: key 1 is not sorted"},
+		{"empty", "", "id\n1\n", []string{"id"}, nil, ErrEmptyCSV.Error()},
+	}
+	for _, test := range tests {
+		_, err := Diff(strings.NewReader(test.a), strings.NewReader(test.b), test.keys, test.opts...)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("%s: got %v, wanted %q", test.name, err, test.err)
+		}
+	}
+}
+
+func TestDiff_output(t *testing.T) {
+	result, err := Diff(strings.NewReader(diffA), strings.NewReader(diffB), []string{"id", "region"}, SortedInputs())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var changeset bytes.Buffer
+	if err := result.WriteChangeset(&changeset); err != nil {
+		t.Fatal(err)
+	}
+	expected := "_op,id,region,name,price,stock\n" +
+		"changed,1,EU,apple,12,\n" +
+		"removed,2,EU,pear,20,\n" +
+		"added,4,US,fig,40,5\n"
+	if changeset.String() != expected {
+		t.Errorf("got changeset\n%s\nwanted\n%s", changeset.String(), expected)
+	}
+
+	var report bytes.Buffer
+	if err := result.WriteReport(&report); err != nil {
+		t.Fatal(err)
+	}
+	expected = "~ 1, EU (lines 2 and 2)\n" +
+		"    price: \"10\" -> \"12\"\n" +
+		"- 2, EU (line 3)\n" +
+		"+ 4, US (line 4)\n" +
+		"1 added, 1 removed, 1 changed\n"
+	if report.String() != expected {
+		t.Errorf("got report\n%s\nwanted\n%s", report.String(), expected)
+	}
+}
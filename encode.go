@@ -1,6 +1,8 @@
 package gocsv
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"reflect"
@@ -14,65 +16,268 @@ func newEncoder(out io.Writer) *encoder {
 	return &encoder{out}
 }
 
+// EncoderOption configures how values are encoded to CSV.
+type EncoderOption func(*encoderOptions)
+
+type encoderOptions struct {
+	omitHeaders    bool
+	encoding       Encoding
+	strictEncoding bool
+	compression    Compression
+	null           string
+	timeLayout     string
+	validateRules  bool
+}
+
+func newEncoderOptions(opts []EncoderOption) *encoderOptions {
+	o := &encoderOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+var (
+	errSQLOptions  = errors.New("gocsv: NullValue and TimeLayout only apply to MarshalRows")
+	errRowsRules   = errors.New("gocsv: ValidateRules does not apply to MarshalRows")
+	errSafeOptions = errors.New("gocsv: Compress and OutputEncoding do not apply to a SafeCSVWriter, wrap its output instead")
+)
+
+// check returns an error if o has options the encoder cannot apply: only
+// MarshalRows applies the SQL ones, and only struct encoders ValidateRules.
+// An encoder writing to a SafeCSVWriter cannot apply the options of the
+// output.
+func (o *encoderOptions) check(sqlRows, toSafeWriter bool) error {
+	switch {
+	case !sqlRows && (o.null != "" || o.timeLayout != ""):
+		return errSQLOptions
+	case sqlRows && o.validateRules:
+		return errRowsRules
+	case toSafeWriter && (o.compression != NoCompression || o.encoding != UTF8 || o.strictEncoding):
+		return errSafeOptions
+	}
+	return nil
+}
+
+// WithoutHeaders omits the header line from the output.
+func WithoutHeaders() EncoderOption {
+	return func(o *encoderOptions) {
+		o.omitHeaders = true
+	}
+}
+
+// OutputEncoding makes MarshalWithOptions write in the encoding enc, see
+// NewEncodingWriter. Characters enc cannot represent are replaced, or fail
+// the encoding with an *EncodingError when strict.
+func OutputEncoding(enc Encoding, strict bool) EncoderOption {
+	return func(o *encoderOptions) {
+		o.encoding = enc
+		o.strictEncoding = strict
+	}
+}
+
+// Compress makes MarshalWithOptions and MarshalPath compress their output
+// with c. Bzip2 is not supported.
+func Compress(c Compression) EncoderOption {
+	return func(o *encoderOptions) {
+		o.compression = c
+	}
+}
+
+// output returns the writer of the CSV text to out, and the func to call
+// once done writing.
+func (o *encoderOptions) output(out io.Writer) (io.Writer, func() error, error) {
+	w, done, err := compress(out, o.compression)
+	if err != nil {
+		return nil, nil, err
+	}
+	if o.encoding != UTF8 || o.strictEncoding {
+		w = NewEncodingWriter(w, o.encoding, o.strictEncoding)
+	}
+	return w, done, nil
+}
+
 func writeFromChan(writer *SafeCSVWriter, c <-chan interface{}) error {
+	return writeFromChanContext(context.Background(), writer, c)
+}
+
+func writeFromChanContext(ctx context.Context, writer *SafeCSVWriter, c <-chan interface{}) error {
 	// Get the first value. It wil determine the header structure.
-	firstValue, ok := <-c
+	firstValue, ok, err := recvContext(ctx, c)
+	if err != nil {
+		return err
+	}
 	if !ok {
 		return fmt.Errorf("channel is closed")
 	}
-	inValue, inType := getConcreteReflectValueAndType(firstValue) // Get the concrete type
+	_, inType := getConcreteReflectValueAndType(firstValue) // Get the concrete type
 	if err := ensureStructOrPtr(inType); err != nil {
 		return err
 	}
-	inInnerWasPointer := inType.Kind() == reflect.Ptr
-	inInnerStructInfo := getStructInfo(inType) // Get the inner struct info to get CSV annotations
-	csvHeadersLabels := make([]string, len(inInnerStructInfo.Fields))
-	for i, fieldInfo := range inInnerStructInfo.Fields { // Used to write the header (first line) in CSV
-		csvHeadersLabels[i] = fieldInfo.getFirstKey()
+	se, err := newStructEncoder(writer, inType)
+	if err != nil {
+		return err
 	}
-	if err := writer.Write(csvHeadersLabels); err != nil {
+	se.columns = structColumns(se.structInfo, se.inType)
+	if err := se.writeHeader(); err != nil {
 		return err
 	}
-	write := func(val reflect.Value) error {
-		if !val.CanAddr() {
-			// Make an addressable copy
-			//
-			// Admittedly this is a kludge but downstream operations (like reflect.Value.Interface())
-			// require addressability
-			ptr := reflect.New(val.Type())
-			ptr.Elem().Set(val)
-			val = ptr.Elem()
+	return encodeChan(ctx, se, c, &firstValue)
+}
+
+func writeFromTypedChan[T any](ctx context.Context, writer *SafeCSVWriter, c <-chan T, opts *encoderOptions) error {
+	var first *T
+	inType := reflect.TypeOf((*T)(nil)).Elem()
+	if inType.Kind() == reflect.Interface {
+		// The header structure can only be determined from the first value.
+		firstValue, ok, err := recvContext(ctx, c)
+		if err != nil {
+			return err
 		}
-		for j, fieldInfo := range inInnerStructInfo.Fields {
-			csvHeadersLabels[j] = ""
-			inInnerFieldValue, err := getInnerField(val, inInnerWasPointer, fieldInfo.IndexChain) // Get the correct field header <-> position
-			if err != nil {
-				return err
-			}
-			csvHeadersLabels[j] = inInnerFieldValue
+		if !ok {
+			writer.Flush()
+			return writer.Error()
 		}
-		if err := writer.Write(csvHeadersLabels); err != nil {
+		_, inType = getConcreteReflectValueAndType(firstValue)
+		first = &firstValue
+	} else if inType.Kind() == reflect.Ptr {
+		inType = inType.Elem()
+	}
+	se, err := newStructEncoder(writer, inType)
+	if err != nil {
+		return err
+	}
+	if opts.validateRules {
+		if err := se.structInfo.rulesError(); err != nil {
 			return err
 		}
-		return nil
+		se.validateRules = true
 	}
-	if err := write(inValue); err != nil {
+	se.columns = structColumns(se.structInfo, se.inType)
+	if !opts.omitHeaders {
+		if err := se.writeHeader(); err != nil {
+			return err
+		}
+	}
+	return encodeChan(ctx, se, c, first)
+}
+
+// structEncoder writes values of one struct type, or pointers to it, as CSV rows.
+type structEncoder struct {
+	writer           *SafeCSVWriter
+	inType           reflect.Type
+	structInfo       *structInfo
+	csvHeadersLabels []string
+	columns          columnPolicy
+	line             int  // Line of the last row written
+	validateRules    bool // Check the rules of the csv tags before writing
+}
+
+func newStructEncoder(writer *SafeCSVWriter, inType reflect.Type) (*structEncoder, error) {
+	if err := ensureInInnerType(inType); err != nil {
+		return nil, err
+	}
+	inInnerStructInfo := getStructInfo(inType) // Get the inner struct info to get CSV annotations
+	return &structEncoder{
+		writer:           writer,
+		inType:           inType,
+		structInfo:       inInnerStructInfo,
+		csvHeadersLabels: make([]string, len(inInnerStructInfo.Fields)),
+	}, nil
+}
+
+func (se *structEncoder) writeHeader() error {
+	for i, fieldInfo := range se.structInfo.Fields { // Used to write the header (first line) in CSV
+		se.csvHeadersLabels[i] = fieldInfo.getFirstKey()
+	}
+	se.line++
+	return se.writer.writeColumns(se.csvHeadersLabels, se.columns)
+}
+
+// write writes v as a CSV row. v must hold the struct type of the encoder, or a non nil pointer to it.
+func (se *structEncoder) write(v interface{}) error {
+	val := reflect.ValueOf(v)
+	if val.Kind() == reflect.Ptr {
+		if val.IsNil() {
+			return fmt.Errorf("cannot write nil %v", val.Type())
+		}
+		val = val.Elem()
+	}
+	if !val.IsValid() || val.Type() != se.inType {
+		return fmt.Errorf("cannot use %v, only %v supported", reflect.TypeOf(v), se.inType)
+	}
+	if !val.CanAddr() {
+		// Make an addressable copy
+		//
+		// Admittedly this is a kludge but downstream operations (like reflect.Value.Interface())
+		// require addressability
+		ptr := reflect.New(val.Type())
+		ptr.Elem().Set(val)
+		val = ptr.Elem()
+	}
+	se.line++
+	if _, err := beforeMarshal(val, se.line); err != nil {
 		return err
 	}
-	for v := range c {
-		val, _ := getConcreteReflectValueAndType(v) // Get the concrete type (not pointer) (Slice<?> or Array<?>)
-		if err := ensureStructOrPtr(inType); err != nil {
+	for j, fieldInfo := range se.structInfo.Fields {
+		se.csvHeadersLabels[j] = ""
+		inInnerFieldValue, err := getInnerField(val, false, fieldInfo.IndexChain) // Get the correct field header <-> position
+		if err != nil {
 			return err
 		}
-		if err := write(val); err != nil {
+		se.csvHeadersLabels[j] = inInnerFieldValue
+	}
+	if se.validateRules {
+		if err := checkRowRules(se.structInfo, se.csvHeadersLabels, se.line); err != nil {
 			return err
 		}
 	}
-	writer.Flush()
-	return writer.Error()
+	return se.writer.writeColumns(se.csvHeadersLabels, se.columns)
+}
+
+// encodeChan writes first, if not nil, then every value received on c, and flushes the writer.
+func encodeChan[T any](ctx context.Context, se *structEncoder, c <-chan T, first *T) error {
+	if first != nil {
+		if err := se.write(*first); err != nil {
+			return err
+		}
+	}
+	for {
+		v, ok, err := recvContext(ctx, c)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		if err := se.write(v); err != nil {
+			return err
+		}
+	}
+	se.writer.Flush()
+	return se.writer.Error()
+}
+
+// recvContext receives the next value from c, giving up when ctx is done first.
+func recvContext[T any](ctx context.Context, c <-chan T) (T, bool, error) {
+	var zero T
+	if err := ctx.Err(); err != nil {
+		return zero, false, err
+	}
+	select {
+	case v, ok := <-c:
+		return v, ok, nil
+	case <-ctx.Done():
+		return zero, false, ctx.Err()
+	}
 }
 
 func writeTo(writer *SafeCSVWriter, in interface{}, omitHeaders bool) error {
+	return writeToWithOptions(writer, in, &encoderOptions{omitHeaders: omitHeaders})
+}
+
+func writeToWithOptions(writer *SafeCSVWriter, in interface{}, opts *encoderOptions) error {
+	omitHeaders := opts.omitHeaders
 	inValue, inType := getConcreteReflectValueAndType(in) // Get the concrete type (not pointer) (Slice<?> or Array<?>)
 	if err := ensureInType(inType); err != nil {
 		return err
@@ -82,26 +287,45 @@ func writeTo(writer *SafeCSVWriter, in interface{}, omitHeaders bool) error {
 		return err
 	}
 	inInnerStructInfo := getStructInfo(inInnerType) // Get the inner struct info to get CSV annotations
+	if opts.validateRules {
+		if err := inInnerStructInfo.rulesError(); err != nil {
+			return err
+		}
+	}
+	columns := structColumns(inInnerStructInfo, inInnerType)
 	csvHeadersLabels := make([]string, len(inInnerStructInfo.Fields))
 	for i, fieldInfo := range inInnerStructInfo.Fields { // Used to write the header (first line) in CSV
 		csvHeadersLabels[i] = fieldInfo.getFirstKey()
 	}
 	if !omitHeaders {
-		if err := writer.Write(csvHeadersLabels); err != nil {
+		if err := writer.writeColumns(csvHeadersLabels, columns); err != nil {
 			return err
 		}
 	}
+	firstLine := 2
+	if omitHeaders {
+		firstLine = 1
+	}
 	inLen := inValue.Len()
 	for i := 0; i < inLen; i++ { // Iterate over container rows
+		inInner, err := beforeMarshal(inValue.Index(i), firstLine+i)
+		if err != nil {
+			return err
+		}
 		for j, fieldInfo := range inInnerStructInfo.Fields {
 			csvHeadersLabels[j] = ""
-			inInnerFieldValue, err := getInnerField(inValue.Index(i), inInnerWasPointer, fieldInfo.IndexChain) // Get the correct field header <-> position
+			inInnerFieldValue, err := getInnerField(inInner, inInnerWasPointer, fieldInfo.IndexChain) // Get the correct field header <-> position
 			if err != nil {
 				return err
 			}
 			csvHeadersLabels[j] = inInnerFieldValue
 		}
-		if err := writer.Write(csvHeadersLabels); err != nil {
+		if opts.validateRules {
+			if err := checkRowRules(inInnerStructInfo, csvHeadersLabels, firstLine+i); err != nil {
+				return err
+			}
+		}
+		if err := writer.writeColumns(csvHeadersLabels, columns); err != nil {
 			return err
 		}
 	}
@@ -109,6 +333,12 @@ func writeTo(writer *SafeCSVWriter, in interface{}, omitHeaders bool) error {
 	return writer.Error()
 }
 
+// structColumns returns the columnPolicy of the fields of t, the struct type
+// of info: the ones tagged `quote:"always"` and the kinds of all of them.
+func structColumns(info *structInfo, t reflect.Type) columnPolicy {
+	return columnPolicy{quoted: info.quotedColumns(), kinds: info.columnKinds(t)}
+}
+
 func ensureStructOrPtr(t reflect.Type) error {
 	switch t.Kind() {
 	case reflect.Struct:
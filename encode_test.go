@@ -2,6 +2,7 @@ package gocsv
 
 import (
 	"bytes"
+	"context"
 	"encoding/csv"
 	"io"
 	"math"
@@ -223,6 +224,71 @@ func Test_writeToChan(t *testing.T) {
 	}
 }
 
+func TestMarshalChanContext(t *testing.T) {
+	b := bytes.Buffer{}
+	c := make(chan interface{})
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		c <- MultiTagSample{Foo: "abc", Bar: 123}
+		// Never close the channel: only the context can stop the writer.
+		cancel()
+	}()
+	if err := MarshalChanContext(ctx, c, NewSafeCSVWriter(csv.NewWriter(&b))); err != context.Canceled {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestMarshalFromChan(t *testing.T) {
+	b := bytes.Buffer{}
+	c := make(chan *MultiTagSample)
+	close(c)
+	if err := MarshalFromChan(context.Background(), c, NewSafeCSVWriter(csv.NewWriter(&b))); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "Baz,BAR\n" {
+		t.Fatalf("expected only the header for an empty channel, got %q", b.String())
+	}
+
+	b.Reset()
+	c = make(chan *MultiTagSample, 2)
+	c <- &MultiTagSample{Foo: "abc", Bar: 123}
+	c <- &MultiTagSample{Foo: "def", Bar: 234}
+	close(c)
+	if err := MarshalFromChan(context.Background(), c, NewSafeCSVWriter(csv.NewWriter(&b)), WithoutHeaders()); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "abc,123\ndef,234\n" {
+		t.Fatalf("expected two rows without header, got %q", b.String())
+	}
+
+	b.Reset()
+	c = make(chan *MultiTagSample, 1)
+	c <- nil
+	close(c)
+	if err := MarshalFromChan(context.Background(), c, NewSafeCSVWriter(csv.NewWriter(&b))); err == nil {
+		t.Fatal("expected an error for a nil value")
+	}
+}
+
+func TestMarshalFromChan_mixedTypes(t *testing.T) {
+	b := bytes.Buffer{}
+	c := make(chan interface{}, 2)
+	c <- MultiTagSample{Foo: "abc", Bar: 123}
+	c <- TagSeparatorSample{Foo: "def", Bar: 234}
+	close(c)
+	if err := MarshalFromChan(context.Background(), c, NewSafeCSVWriter(csv.NewWriter(&b))); err == nil {
+		t.Fatal("expected an error for a value of another type")
+	}
+
+	c = make(chan interface{}, 2)
+	c <- MultiTagSample{Foo: "abc", Bar: 123}
+	c <- TagSeparatorSample{Foo: "def", Bar: 234}
+	close(c)
+	if err := MarshalChan(c, NewSafeCSVWriter(csv.NewWriter(&b))); err == nil {
+		t.Fatal("expected an error for a value of another type")
+	}
+}
+
 // TestRenamedTypes tests for marshaling functions on redefined basic types.
 func TestRenamedTypesMarshal(t *testing.T) {
 	samples := []RenamedSample{
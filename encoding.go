@@ -0,0 +1,323 @@
+package gocsv
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// Encoding is a character encoding of CSV text.
+type Encoding int
+
+const (
+	// UTF8 is UTF-8 without a byte order mark.
+	UTF8 Encoding = iota
+	// UTF8BOM is UTF-8 starting with a byte order mark.
+	UTF8BOM
+	// UTF16LE is little-endian UTF-16.
+	UTF16LE
+	// UTF16BE is big-endian UTF-16.
+	UTF16BE
+	// Windows1252 is the Windows Western European code page.
+	Windows1252
+	// ISO88591 is ISO-8859-1, also known as Latin-1.
+	ISO88591
+)
+
+var encodingNames = [...]string{
+	UTF8:        "UTF-8",
+	UTF8BOM:     "UTF-8 BOM",
+	UTF16LE:     "UTF-16LE",
+	UTF16BE:     "UTF-16BE",
+	Windows1252: "windows-1252",
+	ISO88591:    "ISO-8859-1",
+}
+
+func (e Encoding) String() string {
+	if e >= 0 && int(e) < len(encodingNames) {
+		return encodingNames[e]
+	}
+	return "unknown encoding"
+}
+
+// EncodingError reports bytes that are not valid in an Encoding, or a
+// character that an Encoding cannot represent.
+type EncodingError struct {
+	Encoding Encoding
+	Offset   int64 // Offset in the input of the invalid bytes, when decoding
+	Rune     rune  // Character that cannot be represented, when encoding
+}
+
+func (e *EncodingError) Error() string {
+	if e.Rune != 0 {
+		return fmt.Sprintf("gocsv: cannot encode %q in %v", e.Rune, e.Encoding)
+	}
+	return fmt.Sprintf("gocsv: invalid %v at offset %d", e.Encoding, e.Offset)
+}
+
+// windows1252 maps the bytes 0x80 to 0x9F of Windows-1252 to their
+// characters, 0 for the bytes it does not define. The other bytes are the
+// same as in ISO-8859-1.
+var windows1252 = [32]rune{
+	0x20AC, 0, 0x201A, 0x0192, 0x201E, 0x2026, 0x2020, 0x2021,
+	0x02C6, 0x2030, 0x0160, 0x2039, 0x0152, 0, 0x017D, 0,
+	0, 0x2018, 0x2019, 0x201C, 0x201D, 0x2022, 0x2013, 0x2014,
+	0x02DC, 0x2122, 0x0161, 0x203A, 0x0153, 0, 0x017E, 0x0178,
+}
+
+var windows1252Bytes = func() map[rune]byte {
+	m := make(map[rune]byte, len(windows1252))
+	for i, r := range windows1252 {
+		if r != 0 {
+			m[r] = byte(0x80 + i)
+		}
+	}
+	return m
+}()
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// NewDecodingReader returns a reader of the UTF-8 text of in, which is in the
+// encoding enc. A byte order mark at the start of in is dropped, and takes
+// precedence over enc. Invalid bytes are replaced with U+FFFD, or make Read
+// fail with an *EncodingError when strict.
+func NewDecodingReader(in io.Reader, enc Encoding, strict bool) io.Reader {
+	return decodeInput(bufio.NewReader(in), enc, true, strict)
+}
+
+// decodeInput drops the byte order mark of in, and decodes it from enc or
+// from the encoding of the mark. UTF-8 is only checked when validate.
+func decodeInput(in *bufio.Reader, enc Encoding, validate, strict bool) io.Reader {
+	if bom, _ := in.Peek(len(bomUTF8)); bytes.HasPrefix(bom, bomUTF8) {
+		in.Discard(len(bomUTF8))
+		enc = UTF8
+	} else if bytes.HasPrefix(bom, bomUTF16LE) {
+		in.Discard(len(bomUTF16LE))
+		enc = UTF16LE
+	} else if bytes.HasPrefix(bom, bomUTF16BE) {
+		in.Discard(len(bomUTF16BE))
+		enc = UTF16BE
+	}
+	if (enc == UTF8 || enc == UTF8BOM) && !validate {
+		return in
+	}
+	return &decodingReader{r: in, enc: enc, strict: strict}
+}
+
+type decodingReader struct {
+	r      io.Reader
+	enc    Encoding
+	strict bool
+	chunk  []byte
+	buf    []byte // Bytes read, not decoded yet
+	out    []byte // Text decoded, not returned yet
+	offset int64  // Offset in the input of buf
+	err    error
+}
+
+func (d *decodingReader) Read(p []byte) (int, error) {
+	for len(d.out) == 0 {
+		if d.err != nil {
+			return 0, d.err
+		}
+		if d.chunk == nil {
+			d.chunk = make([]byte, 4096)
+		}
+		n, err := d.r.Read(d.chunk)
+		d.buf = append(d.buf, d.chunk[:n]...)
+		d.err = err
+		if err := d.decode(err != nil); err != nil {
+			d.err = err
+		}
+	}
+	n := copy(p, d.out)
+	d.out = d.out[n:]
+	return n, nil
+}
+
+// decode decodes the runes of buf into out. The bytes of an incomplete rune
+// are kept in buf, unless final.
+func (d *decodingReader) decode(final bool) error {
+	d.out = d.out[:0]
+	i := 0
+	defer func() {
+		d.offset += int64(i)
+		d.buf = append(d.buf[:0], d.buf[i:]...)
+	}()
+	for i < len(d.buf) {
+		r, size, valid := d.next(d.buf[i:], final)
+		if size == 0 {
+			break
+		}
+		if !valid {
+			if d.strict {
+				return &EncodingError{Encoding: d.enc, Offset: d.offset + int64(i)}
+			}
+			r = utf8.RuneError
+		}
+		d.out = utf8.AppendRune(d.out, r)
+		i += size
+	}
+	return nil
+}
+
+// next decodes the first rune of b, of size bytes. size is 0 if b is an
+// incomplete rune and more bytes may follow.
+func (d *decodingReader) next(b []byte, final bool) (r rune, size int, valid bool) {
+	switch d.enc {
+	case UTF16LE, UTF16BE:
+		if len(b) < 2 {
+			if !final {
+				return 0, 0, false
+			}
+			return utf8.RuneError, len(b), false
+		}
+		u := d.unit(b)
+		switch {
+		case 0xDC00 <= u && u <= 0xDFFF: // Low surrogate without high surrogate
+			return utf8.RuneError, 2, false
+		case 0xD800 <= u && u <= 0xDBFF:
+			if len(b) < 4 {
+				if !final {
+					return 0, 0, false
+				}
+				return utf8.RuneError, len(b), false
+			}
+			if r := utf16.DecodeRune(rune(u), rune(d.unit(b[2:]))); r != utf8.RuneError {
+				return r, 4, true
+			}
+			return utf8.RuneError, 2, false
+		}
+		return rune(u), 2, true
+	case Windows1252:
+		if b[0] < 0x80 || b[0] >= 0xA0 {
+			return rune(b[0]), 1, true
+		}
+		r := windows1252[b[0]-0x80]
+		return r, 1, r != 0
+	case ISO88591:
+		return rune(b[0]), 1, true
+	}
+	if b[0] < utf8.RuneSelf {
+		return rune(b[0]), 1, true
+	}
+	if !final && !utf8.FullRune(b) {
+		return 0, 0, false
+	}
+	r, size = utf8.DecodeRune(b)
+	return r, size, r != utf8.RuneError || size > 1
+}
+
+func (d *decodingReader) unit(b []byte) uint16 {
+	if d.enc == UTF16BE {
+		return uint16(b[0])<<8 | uint16(b[1])
+	}
+	return uint16(b[1])<<8 | uint16(b[0])
+}
+
+// NewEncodingWriter returns a writer of UTF-8 text to out in the encoding
+// enc. UTF8BOM, UTF16LE and UTF16BE start with a byte order mark. Characters
+// enc cannot represent, and invalid UTF-8, are replaced with '?' (U+FFFD in
+// UTF-8 and UTF-16), or make Write fail with an *EncodingError when strict.
+func NewEncodingWriter(out io.Writer, enc Encoding, strict bool) io.Writer {
+	w := &encodingWriter{w: out, enc: enc, strict: strict}
+	switch enc {
+	case UTF8BOM:
+		w.bom = bomUTF8
+	case UTF16LE:
+		w.bom = bomUTF16LE
+	case UTF16BE:
+		w.bom = bomUTF16BE
+	}
+	return w
+}
+
+type encodingWriter struct {
+	w      io.Writer
+	enc    Encoding
+	strict bool
+	bom    []byte // Byte order mark still to be written
+	carry  []byte // Incomplete rune at the end of the last Write
+	buf    []byte
+}
+
+func (e *encodingWriter) Write(p []byte) (int, error) {
+	if e.bom != nil {
+		if _, err := e.w.Write(e.bom); err != nil {
+			return 0, err
+		}
+		e.bom = nil
+	}
+	if (e.enc == UTF8 || e.enc == UTF8BOM) && !e.strict {
+		return e.w.Write(p)
+	}
+	b := p
+	if len(e.carry) > 0 {
+		b = append(e.carry, p...)
+	}
+	e.buf = e.buf[:0]
+	i := 0
+	for i < len(b) && utf8.FullRune(b[i:]) {
+		r, size := utf8.DecodeRune(b[i:])
+		ok := false
+		if r != utf8.RuneError || size > 1 {
+			e.buf, ok = e.appendRune(e.buf, r)
+		}
+		if !ok {
+			if e.strict {
+				return 0, &EncodingError{Encoding: e.enc, Rune: r}
+			}
+			replacement := utf8.RuneError
+			if e.enc == Windows1252 || e.enc == ISO88591 {
+				replacement = '?'
+			}
+			e.buf, _ = e.appendRune(e.buf, replacement)
+		}
+		i += size
+	}
+	e.carry = append(e.carry[:0], b[i:]...)
+	if _, err := e.w.Write(e.buf); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (e *encodingWriter) appendUnit(b []byte, u rune) []byte {
+	if e.enc == UTF16BE {
+		return append(b, byte(u>>8), byte(u))
+	}
+	return append(b, byte(u), byte(u>>8))
+}
+
+// appendRune appends r to b in the encoding of e. It reports false if the
+// encoding cannot represent r.
+func (e *encodingWriter) appendRune(b []byte, r rune) ([]byte, bool) {
+	switch e.enc {
+	case UTF16LE, UTF16BE:
+		if r1, r2 := utf16.EncodeRune(r); r1 != utf8.RuneError {
+			return e.appendUnit(e.appendUnit(b, r1), r2), true
+		}
+		return e.appendUnit(b, r), true
+	case Windows1252:
+		if r < 0x80 || (0xA0 <= r && r <= 0xFF) {
+			return append(b, byte(r)), true
+		}
+		if c, ok := windows1252Bytes[r]; ok {
+			return append(b, c), true
+		}
+		return b, false
+	case ISO88591:
+		if r <= 0xFF {
+			return append(b, byte(r)), true
+		}
+		return b, false
+	}
+	return utf8.AppendRune(b, r), true
+}
@@ -0,0 +1,171 @@
+package gocsv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestDecodingReader(t *testing.T) {
+	tests := []struct {
+		name     string
+		in       string
+		enc      Encoding
+		expected string
+	}{
+		{"UTF-8", "café,\xff", UTF8, "café,�"},
+		{"UTF-8 BOM", "\xEF\xBB\xBFcafé", UTF8, "café"},
+		{"UTF-16LE BOM", "\xFF\xFEc\x00a\x00f\x00\xe9\x00=\xd8\x00\xde", UTF8, "café\U0001F600"},
+		{"UTF-16BE BOM", "\xFE\xFF\x00c\x00a\x00f\x00\xe9\xd8=\xde\x00", UTF16LE, "café\U0001F600"},
+		{"UTF-16LE", "c\x00\x00\xdc", UTF16LE, "c�"},
+		{"Windows-1252", "caf\xe9 \x80\x81", Windows1252, "café €�"},
+		{"ISO-8859-1", "caf\xe9 \x80", ISO88591, "café \u0080"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// One byte at a time, to split the runes across reads.
+			out, err := io.ReadAll(NewDecodingReader(iotest.OneByteReader(strings.NewReader(test.in)), test.enc, false))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(out) != test.expected {
+				t.Fatalf("expected %q, got %q", test.expected, out)
+			}
+		})
+	}
+}
+
+func TestDecodingReader_strict(t *testing.T) {
+	out, err := io.ReadAll(NewDecodingReader(strings.NewReader("ab\n\x81c"), Windows1252, true))
+	var encErr *EncodingError
+	if !errors.As(err, &encErr) || encErr.Offset != 3 || encErr.Encoding != Windows1252 {
+		t.Fatalf("expected an encoding error at offset 3, got %v", err)
+	}
+	if string(out) != "ab\n" {
+		t.Fatalf("expected the valid text before the error, got %q", out)
+	}
+}
+
+func TestEncodingWriter(t *testing.T) {
+	tests := []struct {
+		name     string
+		enc      Encoding
+		expected string
+	}{
+		{"UTF-8", UTF8, "café €\U0001F600"},
+		{"UTF-8 BOM", UTF8BOM, "\xEF\xBB\xBFcafé €\U0001F600"},
+		{"UTF-16LE", UTF16LE, "\xFF\xFEc\x00a\x00f\x00\xe9\x00 \x00\xac\x20=\xd8\x00\xde"},
+		{"UTF-16BE", UTF16BE, "\xFE\xFF\x00c\x00a\x00f\x00\xe9\x00 \x20\xac\xd8=\xde\x00"},
+		{"Windows-1252", Windows1252, "caf\xe9 \x80?"},
+		{"ISO-8859-1", ISO88591, "caf\xe9 ??"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := bytes.Buffer{}
+			w := NewEncodingWriter(&b, test.enc, false)
+			// One byte at a time, to split the runes across writes.
+			for _, c := range []byte("café €\U0001F600") {
+				if _, err := w.Write([]byte{c}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if b.String() != test.expected {
+				t.Fatalf("expected %q, got %q", test.expected, b.String())
+			}
+		})
+	}
+}
+
+func TestUnmarshal_byteOrderMark(t *testing.T) {
+	var samples []MultiTagSample
+	if err := UnmarshalString("\uFEFFBaz,BAR\nf,1\n", &samples); err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 1 || samples[0].Foo != "f" {
+		t.Fatalf("unexpected samples %+v", samples)
+	}
+
+	// UTF-16 with a byte order mark is detected.
+	b := bytes.Buffer{}
+	w := NewEncodingWriter(&b, UTF16BE, false)
+	io.WriteString(w, "Baz,BAR\nfé,1\n")
+	samples = nil
+	if err := Unmarshal(&b, &samples); err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 1 || samples[0].Foo != "fé" {
+		t.Fatalf("unexpected samples %+v", samples)
+	}
+
+	um, err := NewUnmarshaller(csv.NewReader(strings.NewReader("\uFEFFBaz,BAR\nf,1\n")), MultiTagSample{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := um.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v.(MultiTagSample).Foo != "f" {
+		t.Fatalf("unexpected sample %+v", v)
+	}
+}
+
+func TestUnmarshalDecoder_inputEncoding(t *testing.T) {
+	var samples []MultiTagSample
+	in := "Baz,BAR\ncaf\xe9,1\n"
+	if err := UnmarshalDecoder(NewDecoder(strings.NewReader(in), InputEncoding(Windows1252, true)), &samples); err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 1 || samples[0].Foo != "café" {
+		t.Fatalf("unexpected samples %+v", samples)
+	}
+
+	// The encoding sniffed is used.
+	samples = nil
+	if err := UnmarshalDecoder(NewDecoder(strings.NewReader(in), SniffDialect(0)), &samples); err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 1 || samples[0].Foo != "café" {
+		t.Fatalf("unexpected samples %+v", samples)
+	}
+
+	err := UnmarshalDecoder(NewDecoder(strings.NewReader(in), InputEncoding(UTF8, true)), &samples)
+	var encErr *EncodingError
+	if !errors.As(err, &encErr) || encErr.Offset != 11 {
+		t.Fatalf("expected an encoding error at offset 11, got %v", err)
+	}
+}
+
+func TestMarshalWithOptions_outputEncoding(t *testing.T) {
+	b := bytes.Buffer{}
+	samples := []MultiTagSample{{Foo: "café", Bar: 1}}
+	if err := MarshalWithOptions(samples, &b, OutputEncoding(ISO88591, true)); err != nil {
+		t.Fatal(err)
+	}
+	if b.String() != "Baz,BAR\ncaf\xe9,1\n" {
+		t.Fatalf("unexpected output %q", b.String())
+	}
+
+	b.Reset()
+	err := MarshalWithOptions([]MultiTagSample{{Foo: "€"}}, &b, OutputEncoding(ISO88591, true), WithoutHeaders())
+	var encErr *EncodingError
+	if !errors.As(err, &encErr) || encErr.Rune != '€' {
+		t.Fatalf("expected an encoding error for '€', got %v", err)
+	}
+
+	b.Reset()
+	if err := MarshalWithOptions(samples, &b, OutputEncoding(UTF16LE, true)); err != nil {
+		t.Fatal(err)
+	}
+	var decoded []MultiTagSample
+	if err := Unmarshal(&b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 1 || decoded[0] != samples[0] {
+		t.Fatalf("unexpected samples %+v", decoded)
+	}
+}
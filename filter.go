@@ -0,0 +1,102 @@
+package gocsv
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// Header is the header of a CSV, the names of its columns.
+type Header []string
+
+// Index returns the position of the first column named name, or -1 if
+// there is none.
+func (h Header) Index(name string) int {
+	for i, n := range h {
+		if n == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Get returns the field of the row in the first column named name, or an
+// empty string if there is none.
+func (h Header) Get(row []string, name string) string {
+	if i := h.Index(name); i >= 0 && i < len(row) {
+		return row[i]
+	}
+	return ""
+}
+
+// Filter makes the decoder skip the rows for which keep returns false,
+// before they are converted. keep must not retain row, which can be reused.
+func Filter(keep func(row []string, headers Header) bool) DecoderOption {
+	return func(o *decoderOptions) {
+		o.filter = keep
+	}
+}
+
+// Where makes the decoder skip the values for which keep returns false,
+// once converted to a T and before the hooks of T are called. The decoded
+// type must be T, or a pointer to T.
+func Where[T any](keep func(*T) bool) DecoderOption {
+	return func(o *decoderOptions) {
+		o.whereType = reflect.TypeOf((*T)(nil)).Elem()
+		o.where = func(v interface{}) bool {
+			return keep(v.(*T))
+		}
+	}
+}
+
+// errRowFiltered is returned by a rowDecoder for the rows that Filter or
+// Where skip.
+var errRowFiltered = errors.New("row filtered")
+
+// rowFilter holds the Filter and Where of a decoding.
+type rowFilter struct {
+	headers Header
+	filter  func([]string, Header) bool
+	where   func(interface{}) bool
+}
+
+// newRowFilter returns the filters of o for the rows after headers, decoded
+// to outInnerType.
+func newRowFilter(o *decoderOptions, outInnerType reflect.Type, headers []string) (rowFilter, error) {
+	if o == nil {
+		return rowFilter{}, nil
+	}
+	if o.where != nil && o.whereType != outInnerType {
+		return rowFilter{}, fmt.Errorf("cannot filter %v with a Where func of *%v", outInnerType, o.whereType)
+	}
+	return rowFilter{headers: headers, filter: o.filter, where: o.where}, nil
+}
+
+// keepRow reports whether the Filter keeps row.
+func (f *rowFilter) keepRow(row []string) bool {
+	return f.filter == nil || f.filter(row, f.headers)
+}
+
+// keepValue reports whether the Where keeps v, a struct or a pointer to it,
+// which must be addressable.
+func (f *rowFilter) keepValue(v reflect.Value) bool {
+	if f.where == nil {
+		return true
+	}
+	if v.Kind() != reflect.Ptr {
+		v = v.Addr()
+	}
+	return f.where(v.Interface())
+}
+
+// decoderOptionsOf returns the options of the decoder, nil if it was not
+// made by NewDecoder.
+func decoderOptionsOf(d interface{}) *decoderOptions {
+	switch d := d.(type) {
+	case *decoder:
+		return &d.opts
+	case contextDecoder:
+		return decoderOptionsOf(d.SimpleDecoder)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package gocsv
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+type filteredOrder struct {
+	ID      int    `csv:"id"`
+	Country string `csv:"country"`
+	Amount  int    `csv:"amount"`
+}
+
+const filteredCSV = "id,country,amount\n1,FR,10\n2,US,20\n3,FR,bad\n4,FR,40\n"
+
+func frenchOrders(row []string, headers Header) bool {
+	return headers.Get(row, "country") == "FR"
+}
+
+func bigOrders(o *filteredOrder) bool {
+	return o.Amount >= 20
+}
+
+func orderIDs[T any](orders []T, id func(T) int) []int {
+	ids := []int{}
+	for _, o := range orders {
+		ids = append(ids, id(o))
+	}
+	return ids
+}
+
+func TestFilter_unmarshal(t *testing.T) {
+	in := "id,country,amount\n1,FR,10\n2,US,bad\n3,FR,30\n4,DE,40\n"
+	for _, native := range []bool{true, false} {
+		if !native {
+			SetCSVReader(func(in io.Reader) CSVReader { return csv.NewReader(in) })
+			defer SetCSVReader(DefaultCSVReader)
+		}
+		decoder := NewDecoder(strings.NewReader(in), Filter(frenchOrders))
+		orders := make([]*filteredOrder, 10)
+		if err := UnmarshalDecoder(decoder, &orders); err != nil {
+			t.Fatalf("native %v: %v", native, err)
+		}
+		ids := orderIDs(orders, func(o *filteredOrder) int { return o.ID })
+		if len(ids) != 2 || ids[0] != 1 || ids[1] != 3 {
+			t.Errorf("native %v: got orders %v, wanted [1 3]", native, ids)
+		}
+	}
+
+	var orders []filteredOrder
+	err := UnmarshalDecoder(NewDecoder(strings.NewReader(filteredCSV), Where(bigOrders)), &orders)
+	var parseErr *csv.ParseError
+	if !errors.As(err, &parseErr) || parseErr.Line != 4 {
+		t.Errorf("Where: got %v, wanted the conversion error of line 4", err)
+	}
+	decoder := NewDecoder(strings.NewReader(filteredCSV), Filter(frenchOrders), Where(bigOrders))
+	if err := UnmarshalDecoder(decoder, &orders); err == nil {
+		t.Error("conversion error of a kept row not reported")
+	}
+	in = "id,country,amount\n1,FR,10\n2,US,20\n3,FR,30\n"
+	decoder = NewDecoder(strings.NewReader(in), Filter(frenchOrders), Where(bigOrders))
+	if err := UnmarshalDecoder(decoder, &orders); err != nil {
+		t.Fatal(err)
+	}
+	if ids := orderIDs(orders, func(o filteredOrder) int { return o.ID }); len(ids) != 1 || ids[0] != 3 {
+		t.Errorf("Filter and Where: got orders %v, wanted [3]", ids)
+	}
+
+	type other struct {
+		ID int `csv:"id"`
+	}
+	decoder = NewDecoder(strings.NewReader(in), Where(func(*other) bool { return true }))
+	if err := UnmarshalDecoder(decoder, &orders); err == nil {
+		t.Error("Where of another type accepted")
+	}
+}
+
+func TestFilter_chanAndCallback(t *testing.T) {
+	in := "id,country,amount\n1,FR,10\n2,US,bad\n3,FR,30\n4,FR,40\n"
+	c := make(chan filteredOrder, 10)
+	decoder := NewDecoder(strings.NewReader(in), Filter(frenchOrders), Where(bigOrders))
+	if err := UnmarshalDecoderToChan(decoder, c); err != nil {
+		t.Fatal(err)
+	}
+	var ids []int
+	for o := range c {
+		ids = append(ids, o.ID)
+	}
+	if len(ids) != 2 || ids[0] != 3 || ids[1] != 4 {
+		t.Errorf("UnmarshalDecoderToChan: got orders %v, wanted [3 4]", ids)
+	}
+
+	ids = nil
+	decoder = NewDecoder(strings.NewReader(in), Filter(frenchOrders))
+	err := UnmarshalDecoderToCallback(decoder, func(o *filteredOrder) {
+		ids = append(ids, o.ID)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 3 || ids[2] != 4 {
+		t.Errorf("UnmarshalDecoderToCallback: got orders %v, wanted [1 3 4]", ids)
+	}
+}
+
+func TestFilter_unmarshaller(t *testing.T) {
+	in := "id,country,amount\n1,FR,10\n2,US,bad\n3,FR,30\n4,FR,40\n"
+	um, err := NewUnmarshaller(csv.NewReader(strings.NewReader(in)), filteredOrder{}, Filter(frenchOrders), Where(bigOrders))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []int
+	for {
+		v, err := um.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, v.(filteredOrder).ID)
+	}
+	if len(ids) != 2 || ids[0] != 3 || ids[1] != 4 {
+		t.Errorf("got orders %v, wanted [3 4]", ids)
+	}
+
+	if _, err := NewUnmarshaller(csv.NewReader(strings.NewReader(in)), &filteredOrder{}, Where(func(*Header) bool { return true })); err == nil {
+		t.Error("Where of another type accepted")
+	}
+}
@@ -0,0 +1,325 @@
+package gocsv
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// FixedWidthError is returned by MarshalFixed when a value is longer than its
+// field, and the field does not allow truncation.
+type FixedWidthError struct {
+	Line  int    // Line of the value, the first one is 1
+	Field string // First key of the field
+	Value string
+	Len   int // Length of the field
+}
+
+func (e *FixedWidthError) Error() string {
+	return fmt.Sprintf("line %d: value %q of field %s overflows its %d characters", e.Line, e.Value, e.Field, e.Len)
+}
+
+// FixedTruncationError is returned by MarshalFixed with ReportTruncation,
+// once all the lines are written, when values longer than their field were
+// truncated to fit, the field having the truncate option. Truncated holds
+// each of these values.
+type FixedTruncationError struct {
+	Truncated []FixedWidthError
+}
+
+func (e *FixedTruncationError) Error() string {
+	first := e.Truncated[0]
+	if len(e.Truncated) == 1 {
+		return fmt.Sprintf("truncated %s", first.Error())
+	}
+	return fmt.Sprintf("truncated %d values, the first one on %s", len(e.Truncated), first.Error())
+}
+
+// fixedColumn is the position of a field in the fixed-width format, parsed
+// from a tag like `fixed:"start=1,len=10,align=right,pad=0,truncate"`.
+// start is the position of the first character, from 1.
+type fixedColumn struct {
+	fieldInfo
+	start, len int
+	alignRight bool
+	pad        rune
+	truncate   bool
+}
+
+func parseFixedTag(f fieldInfo) (fixedColumn, error) {
+	c := fixedColumn{fieldInfo: f, pad: ' '}
+	for _, option := range strings.Split(f.fixed, ",") {
+		name, value, _ := strings.Cut(option, "=")
+		var err error
+		switch name {
+		case "start":
+			c.start, err = strconv.Atoi(value)
+		case "len":
+			c.len, err = strconv.Atoi(value)
+		case "align":
+			switch value {
+			case "left":
+			case "right":
+				c.alignRight = true
+			default:
+				err = errors.New("align must be left or right")
+			}
+		case "pad":
+			var size int
+			c.pad, size = utf8.DecodeRuneInString(value)
+			if size == 0 || size != len(value) {
+				err = errors.New("pad must be one character")
+			}
+		case "truncate":
+			c.truncate = true
+		default:
+			err = errors.New("unknown option")
+		}
+		if err != nil {
+			return c, fmt.Errorf("field %s: invalid fixed tag option %q: %v", f.getFirstKey(), option, err)
+		}
+	}
+	if c.start < 1 || c.len < 1 {
+		return c, fmt.Errorf("field %s: fixed tag needs a start and a len of at least 1", f.getFirstKey())
+	}
+	return c, nil
+}
+
+// getFixedColumns returns the fixed-width columns of the struct fields with
+// a fixed tag, and the width of a line.
+func getFixedColumns(info *structInfo) ([]fixedColumn, int, error) {
+	var columns []fixedColumn
+	width := 0
+	for _, f := range info.Fields {
+		if f.fixed == "" {
+			continue
+		}
+		c, err := parseFixedTag(f)
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, other := range columns {
+			if c.start < other.start+other.len && other.start < c.start+c.len {
+				return nil, 0, fmt.Errorf("fields %s and %s overlap", other.getFirstKey(), c.getFirstKey())
+			}
+		}
+		columns = append(columns, c)
+		if end := c.start + c.len - 1; end > width {
+			width = end
+		}
+	}
+	if len(columns) == 0 {
+		return nil, 0, errors.New("no fixed struct tags found")
+	}
+	return columns, width, nil
+}
+
+// value returns the value of the column in the line, without its padding:
+// the pad characters are only trimmed on the side of the padding, the left
+// when aligned right and the right otherwise, but for the last one of a
+// column of pads other than spaces.
+func (c *fixedColumn) value(line []rune) string {
+	start, end := c.start-1, c.start-1+c.len
+	if start >= len(line) {
+		return ""
+	}
+	if end > len(line) {
+		end = len(line)
+	}
+	value := string(line[start:end])
+	trimmed := strings.TrimRight(value, string(c.pad))
+	if c.alignRight {
+		trimmed = strings.TrimLeft(value, string(c.pad))
+	}
+	if trimmed == "" && value != "" && c.pad != ' ' {
+		return string(c.pad) // A zero padded with zeros
+	}
+	return trimmed
+}
+
+// put writes the value of the column in the line, padded to its length. It
+// returns the *FixedWidthError of a value longer than the column, and whether
+// the value was truncated instead. A value padded with another character
+// than a space is an error if value would not read it back, as "10" padded
+// with zeros on the right: the spaces around the values are not kept.
+func (c *fixedColumn) put(line []rune, value string, lineNum int) (truncated bool, err error) {
+	runes := []rune(value)
+	if len(runes) > c.len {
+		if !c.truncate {
+			return false, &FixedWidthError{Line: lineNum, Field: c.getFirstKey(), Value: value, Len: c.len}
+		}
+		runes = runes[:c.len]
+		truncated = true
+	}
+	field := line[c.start-1 : c.start-1+c.len]
+	padding := c.len - len(runes)
+	if !c.alignRight {
+		padding = 0
+	}
+	for i := range field {
+		field[i] = c.pad
+	}
+	copy(field[padding:], runes)
+	if c.pad != ' ' && !truncated && c.value(line) != value {
+		return false, fmt.Errorf("line %d: value %q of field %s cannot be read back once padded with %q", lineNum, value, c.getFirstKey(), c.pad)
+	}
+	return truncated, nil
+}
+
+// UnmarshalFixed parses the fixed-width lines from the reader in the interface.
+// Only the struct fields with a fixed tag are set. Empty lines are skipped.
+func UnmarshalFixed(in io.Reader, out interface{}) error {
+	outValue, outType := getConcreteReflectValueAndType(out) // Get the concrete type (not pointer) (Slice<?> or Array<?>)
+	if err := ensureOutType(outType); err != nil {
+		return err
+	}
+	outInnerWasPointer, outInnerType := getConcreteContainerInnerType(outType) // Get the concrete inner type (not pointer) (Container<"?">)
+	if err := ensureOutInnerType(outInnerType); err != nil {
+		return err
+	}
+	var values []reflect.Value
+	err := readFixed(in, outInnerWasPointer, outInnerType, func(v reflect.Value) error {
+		values = append(values, v)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if err := ensureOutCapacity(&outValue, len(values)+1); err != nil { // Ensure the container is big enough, as for a CSV with a header
+		return err
+	}
+	for i, v := range values {
+		outValue.Index(i).Set(v)
+	}
+	return nil
+}
+
+// UnmarshalFixedToCallback parses the fixed-width lines from the reader and send each value to the given func f.
+// The func must look like func(Struct).
+func UnmarshalFixedToCallback(in io.Reader, f interface{}) error {
+	valueFunc := reflect.ValueOf(f)
+	t := reflect.TypeOf(f)
+	if t.NumIn() != 1 {
+		return fmt.Errorf("the given function must have exactly one parameter")
+	}
+	outInnerType := t.In(0)
+	outInnerWasPointer := outInnerType.Kind() == reflect.Ptr
+	if outInnerWasPointer {
+		outInnerType = outInnerType.Elem()
+	}
+	if err := ensureOutInnerType(outInnerType); err != nil {
+		return err
+	}
+	return readFixed(in, outInnerWasPointer, outInnerType, func(v reflect.Value) error {
+		valueFunc.Call([]reflect.Value{v})
+		return nil
+	})
+}
+
+func readFixed(in io.Reader, outInnerWasPointer bool, outInnerType reflect.Type, emit func(reflect.Value) error) error {
+	columns, _, err := getFixedColumns(getStructInfo(outInnerType))
+	if err != nil {
+		return err
+	}
+	r := bufio.NewReader(in)
+	for lineNum := 1; ; lineNum++ {
+		line, err := r.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if text := strings.TrimRight(line, "\r\n"); text != "" {
+			runes := []rune(text)
+			outInner := createNewOutInner(outInnerWasPointer, outInnerType)
+			for i := range columns {
+				c := &columns[i]
+				if err := setInnerField(&outInner, outInnerWasPointer, c.IndexChain, c.value(runes), c.omitEmpty); err != nil {
+					return &csv.ParseError{Line: lineNum, Column: c.start, Err: err}
+				}
+			}
+			if err := emit(outInner); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			return nil
+		}
+	}
+}
+
+// FixedOption configures MarshalFixed.
+type FixedOption func(*fixedOptions)
+
+type fixedOptions struct {
+	reportTruncation bool
+}
+
+// ReportTruncation makes MarshalFixed return a *FixedTruncationError with
+// the values truncated by the fields with the truncate option, once all the
+// lines are written.
+func ReportTruncation() FixedOption {
+	return func(o *fixedOptions) {
+		o.reportTruncation = true
+	}
+}
+
+// MarshalFixed writes the interface as fixed-width lines in the writer. Only
+// the struct fields with a fixed tag are written, the gaps between them are
+// filled with spaces. A value longer than its field is a *FixedWidthError,
+// unless the field has the truncate option: it is then truncated, and only
+// reported with ReportTruncation.
+func MarshalFixed(in interface{}, out io.Writer, opts ...FixedOption) error {
+	var o fixedOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	inValue, inType := getConcreteReflectValueAndType(in) // Get the concrete type (not pointer) (Slice<?> or Array<?>)
+	if err := ensureInType(inType); err != nil {
+		return err
+	}
+	inInnerWasPointer, inInnerType := getConcreteContainerInnerType(inType) // Get the concrete inner type (not pointer) (Container<"?">)
+	if err := ensureInInnerType(inInnerType); err != nil {
+		return err
+	}
+	columns, width, err := getFixedColumns(getStructInfo(inInnerType))
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(out)
+	line := make([]rune, width)
+	var truncated []FixedWidthError
+	for i := 0; i < inValue.Len(); i++ {
+		for j := range line {
+			line[j] = ' '
+		}
+		for j := range columns {
+			c := &columns[j]
+			value, err := getInnerField(inValue.Index(i), inInnerWasPointer, c.IndexChain)
+			if err != nil {
+				return err
+			}
+			cut, err := c.put(line, value, i+1)
+			if err != nil {
+				return err
+			}
+			if cut {
+				truncated = append(truncated, FixedWidthError{Line: i + 1, Field: c.getFirstKey(), Value: value, Len: c.len})
+			}
+		}
+		if _, err := w.WriteString(string(line) + "\n"); err != nil {
+			return err
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if o.reportTruncation && truncated != nil {
+		return &FixedTruncationError{Truncated: truncated}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package gocsv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type fixedSample struct {
+	Name   string  `csv:"name" fixed:"start=1,len=8"`
+	Amount int     `csv:"amount" fixed:"start=10,len=6,align=right,pad=0"`
+	Rate   float64 `csv:"rate" fixed:"start=16,len=5,align=right"`
+	Note   string  `csv:"note" fixed:"start=21,len=4,truncate"`
+	Extra  string  `csv:"extra"`
+}
+
+func TestMarshalFixed(t *testing.T) {
+	samples := []fixedSample{
+		{Name: "café", Amount: 42, Rate: 1.5, Note: "abcdef", Extra: "ignored"},
+		{Name: "b", Amount: 123456},
+	}
+	if err := MarshalFixed(samples, &bytes.Buffer{}); err != nil {
+		t.Fatalf("truncation reported without ReportTruncation: %v", err)
+	}
+	b := bytes.Buffer{}
+	err := MarshalFixed(samples, &b, ReportTruncation())
+	var truncErr *FixedTruncationError
+	if !errors.As(err, &truncErr) || len(truncErr.Truncated) != 1 {
+		t.Fatalf("expected the truncation of a note, got %v", err)
+	}
+	if cut := truncErr.Truncated[0]; cut.Line != 1 || cut.Field != "note" || cut.Value != "abcdef" || cut.Len != 4 {
+		t.Fatalf("unexpected truncation %+v", cut)
+	}
+	expected := "café     000042  1.5abcd\n" +
+		"b        123456    0    \n"
+	if b.String() != expected {
+		t.Fatalf("expected\n%q, got\n%q", expected, b.String())
+	}
+
+	var decoded []fixedSample
+	if err := UnmarshalFixed(&b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	samples[0].Note, samples[0].Extra = "abcd", ""
+	if len(decoded) != 2 || decoded[0] != samples[0] || decoded[1] != samples[1] {
+		t.Fatalf("unexpected samples %+v", decoded)
+	}
+}
+
+func TestMarshalFixed_overflow(t *testing.T) {
+	b := bytes.Buffer{}
+	err := MarshalFixed([]fixedSample{{Name: "a"}, {Name: "too long name"}}, &b)
+	var widthErr *FixedWidthError
+	if !errors.As(err, &widthErr) || widthErr.Line != 2 || widthErr.Field != "name" || widthErr.Len != 8 {
+		t.Fatalf("expected an overflow of name on line 2, got %v", err)
+	}
+}
+
+func TestMarshalFixed_padding(t *testing.T) {
+	type padded struct {
+		Code  string `fixed:"start=1,len=5,pad=0"`
+		Count int    `fixed:"start=6,len=5,align=right,pad=0"`
+		Ref   string `fixed:"start=11,len=5,align=right,pad=-"`
+	}
+	samples := []padded{{"007", 100, "a-b"}, {"9", 0, "-"}}
+	b := bytes.Buffer{}
+	if err := MarshalFixed(samples, &b); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "0070000100--a-b\n9000000000-----\n"; b.String() != expected {
+		t.Fatalf("expected %q, got %q", expected, b.String())
+	}
+	var decoded []padded
+	if err := UnmarshalFixed(&b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 2 || decoded[0] != samples[0] || decoded[1] != samples[1] {
+		t.Fatalf("unexpected samples %+v", decoded)
+	}
+
+	for _, sample := range []padded{{Code: "10"}, {Ref: "-a"}, {Ref: "--"}} {
+		if err := MarshalFixed([]padded{sample}, &bytes.Buffer{}); err == nil || !strings.Contains(err.Error(), "read back") {
+			t.Errorf("%+v: expected an error, got %v", sample, err)
+		}
+	}
+}
+
+func TestUnmarshalFixed_errors(t *testing.T) {
+	var samples []fixedSample
+	err := UnmarshalFixed(strings.NewReader("a        000001\n\nb        00x002\n"), &samples)
+	var parseErr *csv.ParseError
+	if !errors.As(err, &parseErr) || parseErr.Line != 3 || parseErr.Column != 10 {
+		t.Fatalf("expected an error on line 3, column 10, got %v", err)
+	}
+
+	type overlapping struct {
+		A string `fixed:"start=1,len=5"`
+		B string `fixed:"start=5,len=5"`
+	}
+	if err := UnmarshalFixed(strings.NewReader("a\n"), &[]overlapping{}); err == nil || !strings.Contains(err.Error(), "overlap") {
+		t.Fatalf("expected an overlap error, got %v", err)
+	}
+
+	type invalid struct {
+		A string `fixed:"start=1,len=5,align=center"`
+	}
+	if err := UnmarshalFixed(strings.NewReader("a\n"), &[]invalid{}); err == nil || !strings.Contains(err.Error(), "align") {
+		t.Fatalf("expected an align error, got %v", err)
+	}
+}
+
+func TestUnmarshalFixedToCallback(t *testing.T) {
+	var names []string
+	err := UnmarshalFixedToCallback(strings.NewReader("a       \r\nbc"), func(s *fixedSample) {
+		names = append(names, s.Name)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 || names[0] != "a" || names[1] != "bc" {
+		t.Fatalf("unexpected names %q", names)
+	}
+}
@@ -0,0 +1,380 @@
+// Package gocsvlint defines an Analyzer that checks the csv tags of the
+// struct types passed to the gocsv functions.
+//
+// The mistakes it reports are otherwise only seen at run time, if ever:
+//
+//   - two fields with the same key, of which gocsv only fills the first,
+//   - fields of a type gocsv cannot convert from or to a string,
+//   - tags using another separator than gocsv.TagSeparator, like
+//     `csv:"name,omitempty"` when TagSeparator is "|",
+//   - unexported fields with a csv tag, that gocsv ignores.
+//
+// The Analyzer is run by the gocsvlint command, with go vet:
+//
+//	go vet -vettool=$(which gocsvlint) ./...
+//
+// The TagSeparator is "," unless the package assigns a constant to
+// gocsv.TagSeparator, or the -tagseparator flag is given.
+package gocsvlint
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/token"
+	"go/types"
+	"reflect"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+const gocsvPath = "github.com/gocarina/gocsv"
+
+// Analyzer checks the csv tags of the struct types passed to the gocsv functions.
+var Analyzer = &analysis.Analyzer{
+	Name:     "gocsvlint",
+	Doc:      "check the csv tags of the struct types passed to gocsv functions",
+	URL:      "https://pkg.go.dev/github.com/gocarina/gocsv/gocsvlint",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+var tagSeparator string
+
+func init() {
+	Analyzer.Flags.StringVar(&tagSeparator, "tagseparator", "", "the gocsv.TagSeparator of the checked packages, \",\" by default")
+}
+
+// direction is how a gocsv function converts the struct fields.
+type direction int
+
+const (
+	decoding direction = 1 << iota
+	encoding
+)
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	inspect := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	c := &checker{
+		pass:      pass,
+		separator: tagSeparator,
+		checked:   make(map[types.Type]direction),
+		reported:  make(map[*types.Var]bool),
+	}
+	if c.separator == "" {
+		c.separator = assignedTagSeparator(pass, inspect)
+	}
+
+	inspect.Preorder([]ast.Node{(*ast.CallExpr)(nil)}, func(n ast.Node) {
+		call := n.(*ast.CallExpr)
+		fn, ok := typeutil.Callee(pass.TypesInfo, call).(*types.Func)
+		if !ok || fn.Pkg() == nil || fn.Pkg().Path() != gocsvPath {
+			return
+		}
+		dir := decoding | encoding
+		switch name := fn.Name(); {
+		case strings.Contains(name, "Unmarshal"), strings.HasPrefix(name, "Read"):
+			dir = decoding
+		case strings.Contains(name, "Marshal"), strings.HasPrefix(name, "Write"):
+			dir = encoding
+		}
+		for _, arg := range call.Args {
+			c.checkType(pass.TypesInfo.TypeOf(arg), dir, arg.Pos())
+		}
+		if inst, ok := pass.TypesInfo.Instances[calleeIdent(call.Fun)]; ok {
+			for i := 0; i < inst.TypeArgs.Len(); i++ {
+				c.checkType(inst.TypeArgs.At(i), dir, call.Pos())
+			}
+		}
+	})
+	return nil, nil
+}
+
+// calleeIdent returns the identifier of a function, possibly instantiated, or nil.
+func calleeIdent(fun ast.Expr) *ast.Ident {
+	switch fun := ast.Unparen(fun).(type) {
+	case *ast.Ident:
+		return fun
+	case *ast.SelectorExpr:
+		return fun.Sel
+	case *ast.IndexExpr:
+		return calleeIdent(fun.X)
+	case *ast.IndexListExpr:
+		return calleeIdent(fun.X)
+	}
+	return nil
+}
+
+// assignedTagSeparator returns the constant assigned to gocsv.TagSeparator
+// in the package, or ",".
+func assignedTagSeparator(pass *analysis.Pass, inspect *inspector.Inspector) string {
+	separator := ","
+	inspect.Preorder([]ast.Node{(*ast.AssignStmt)(nil)}, func(n ast.Node) {
+		assign := n.(*ast.AssignStmt)
+		if len(assign.Lhs) != len(assign.Rhs) {
+			return
+		}
+		for i, lhs := range assign.Lhs {
+			sel, ok := ast.Unparen(lhs).(*ast.SelectorExpr)
+			if !ok {
+				continue
+			}
+			v, ok := pass.TypesInfo.Uses[sel.Sel].(*types.Var)
+			if !ok || v.Pkg() == nil || v.Pkg().Path() != gocsvPath || v.Name() != "TagSeparator" {
+				continue
+			}
+			if tv := pass.TypesInfo.Types[assign.Rhs[i]]; tv.Value != nil && tv.Value.Kind() == constant.String {
+				separator = constant.StringVal(tv.Value)
+			}
+		}
+	})
+	return separator
+}
+
+type checker struct {
+	pass      *analysis.Pass
+	separator string
+	checked   map[types.Type]direction // Directions in which the struct types were checked
+	reported  map[*types.Var]bool      // Fields reported with no conversion
+}
+
+// checkType checks the struct types of values of type t, like the element
+// type of a slice or of a channel, or the parameter of a callback.
+func (c *checker) checkType(t types.Type, dir direction, pos token.Pos) {
+	if t == nil {
+		return
+	}
+	switch u := t.Underlying().(type) {
+	case *types.Pointer:
+		c.checkType(u.Elem(), dir, pos)
+	case *types.Slice:
+		c.checkType(u.Elem(), dir, pos)
+	case *types.Array:
+		c.checkType(u.Elem(), dir, pos)
+	case *types.Chan:
+		c.checkType(u.Elem(), dir, pos)
+	case *types.Signature:
+		for i := 0; i < u.Params().Len(); i++ {
+			c.checkType(u.Params().At(i).Type(), dir, pos)
+		}
+	case *types.Struct:
+		named, ok := types.Unalias(t).(*types.Named)
+		if !ok || isLibraryType(named) {
+			return
+		}
+		checked, ok := c.checked[t]
+		if ok && checked&dir == dir {
+			return
+		}
+		c.checked[t] = checked | dir
+		c.checkStruct(t, u, dir, !ok, pos)
+	}
+}
+
+// isLibraryType reports whether t is declared in gocsv or the standard
+// library, like the options and the readers passed to gocsv, which are not
+// mapped to CSV.
+func isLibraryType(t *types.Named) bool {
+	pkg := t.Obj().Pkg()
+	if pkg == nil {
+		return true
+	}
+	first, _, _ := strings.Cut(pkg.Path(), "/")
+	return pkg.Path() == gocsvPath || !strings.Contains(first, ".")
+}
+
+// field is a field mapped to a CSV column, as by getFieldInfos.
+type field struct {
+	v    *types.Var
+	keys []string
+}
+
+// checkStruct checks the fields of the struct type t can be converted in
+// the direction, and when first checked, its tags.
+func (c *checker) checkStruct(t types.Type, s *types.Struct, dir direction, first bool, pos token.Pos) {
+	fields := c.fields(t, s, first, pos)
+	firsts := make(map[string]*types.Var)
+	for _, f := range fields {
+		for _, key := range f.keys {
+			if prev, ok := firsts[key]; ok && prev != f.v {
+				if first {
+					c.report(f.v.Pos(), pos, "duplicate csv key %q in %s: field %s is ignored, %s is used", key, c.typeString(t), f.v.Name(), prev.Name())
+				}
+				continue
+			}
+			firsts[key] = f.v
+		}
+		if !c.reported[f.v] && !convertible(f.v.Type(), dir) {
+			c.reported[f.v] = true
+			c.report(f.v.Pos(), pos, "field %s of %s: %s has no conversion %s", f.v.Name(), c.typeString(t), c.typeString(f.v.Type()), dir)
+		}
+	}
+}
+
+// typeString returns t qualified by the packages other than the checked one.
+func (c *checker) typeString(t types.Type) string {
+	return types.TypeString(t, types.RelativeTo(c.pass.Pkg))
+}
+
+func (d direction) String() string {
+	switch d {
+	case decoding:
+		return "from a string"
+	case encoding:
+		return "to a string"
+	}
+	return "from nor to a string"
+}
+
+// fields returns the fields gocsv maps to columns. When report, it reports
+// the unexported fields with a csv tag and the tags using another separator.
+func (c *checker) fields(t types.Type, s *types.Struct, report bool, pos token.Pos) []field {
+	var fields []field
+	for i := 0; i < s.NumFields(); i++ {
+		v := s.Field(i)
+		tag, hasTag := reflect.StructTag(s.Tag(i)).Lookup("csv")
+		if !v.Exported() {
+			if hasTag && report {
+				c.report(v.Pos(), pos, "unexported field %s of %s has a csv tag, but is ignored", v.Name(), c.typeString(t))
+			}
+			continue
+		}
+		if v.Anonymous() {
+			if es, ok := v.Type().Underlying().(*types.Struct); ok {
+				fields = append(fields, c.fields(v.Type(), es, report, pos)...)
+				continue
+			}
+		}
+		if report {
+			c.checkSeparator(v, tag, pos)
+		}
+		var keys []string
+		for _, key := range strings.Split(tag, c.separator) {
+			if strings.HasPrefix(key, "pattern=") {
+				break // The pattern is the rest of the tag
+			}
+			if key != "omitempty" && !isRuleOption(key) {
+				keys = append(keys, key)
+			}
+		}
+		switch {
+		case len(keys) == 1 && keys[0] == "-":
+			continue
+		case len(keys) == 0 || keys[0] == "":
+			keys = []string{v.Name()}
+		}
+		fields = append(fields, field{v: v, keys: keys})
+	}
+	return fields
+}
+
+// isRuleOption reports whether the entry of a csv tag is a validation rule,
+// like max=150, rather than a key. It must be kept in sync with the
+// isRuleOption of gocsv, which gocsvlint does not import.
+func isRuleOption(entry string) bool {
+	name, _, ok := strings.Cut(entry, "=")
+	if !ok {
+		return false
+	}
+	switch name {
+	case "min", "max", "minlen", "maxlen", "oneof", "pattern":
+		return true
+	}
+	return false
+}
+
+// otherSeparators are the separators likely used in tags by mistake.
+var otherSeparators = []string{",", "|", ";", " "}
+
+// checkSeparator reports the tags in which the options are separated by
+// another separator than the TagSeparator, and are part of the key instead.
+func (c *checker) checkSeparator(v *types.Var, tag string, pos token.Pos) {
+	for _, other := range otherSeparators {
+		if other == c.separator || !strings.Contains(tag, other+"omitempty") {
+			continue
+		}
+		for _, key := range strings.Split(tag, c.separator) {
+			if strings.HasSuffix(key, other+"omitempty") {
+				c.report(v.Pos(), pos, "csv tag %q of field %s separates omitempty with %q, but the TagSeparator is %q", tag, v.Name(), other, c.separator)
+				return
+			}
+		}
+	}
+}
+
+// report reports a diagnostic at the position of the field when in the
+// checked package, or at the position of the call otherwise.
+func (c *checker) report(fieldPos, callPos token.Pos, format string, args ...interface{}) {
+	pos := callPos
+	if f := c.pass.Fset.File(fieldPos); f != nil {
+		for _, file := range c.pass.Files {
+			if c.pass.Fset.File(file.Pos()) == f {
+				pos = fieldPos
+				break
+			}
+		}
+	}
+	c.pass.Reportf(pos, format, args...)
+}
+
+// convertible reports whether gocsv converts values of type t in the
+// direction: the basic types but complex numbers and uintptr, and the types
+// implementing the gocsv or encoding interfaces.
+func convertible(t types.Type, dir direction) bool {
+	for {
+		p, ok := t.Underlying().(*types.Pointer)
+		if !ok {
+			break
+		}
+		if hasMethods(t, dir) {
+			return true
+		}
+		t = p.Elem()
+	}
+	if _, ok := t.Underlying().(*types.Interface); ok {
+		return true // Depends on the dynamic type
+	}
+	if b, ok := t.Underlying().(*types.Basic); ok {
+		switch {
+		case b.Info()&types.IsComplex != 0, b.Kind() == types.Uintptr, b.Kind() == types.UnsafePointer:
+		default:
+			return true
+		}
+	}
+	return hasMethods(t, dir) || hasMethods(types.NewPointer(t), dir)
+}
+
+// hasMethods reports whether t has a method converting it in any of the directions.
+func hasMethods(t types.Type, dir direction) bool {
+	ms := types.NewMethodSet(t)
+	has := func(name string, params, results []string) bool {
+		sel := ms.Lookup(nil, name)
+		if sel == nil {
+			return false
+		}
+		sig := sel.Type().(*types.Signature)
+		return typeNames(sig.Params()) == strings.Join(params, ",") &&
+			typeNames(sig.Results()) == strings.Join(results, ",")
+	}
+	if dir&decoding != 0 && (has("UnmarshalCSV", []string{"string"}, []string{"error"}) ||
+		has("UnmarshalText", []string{"[]byte"}, []string{"error"})) {
+		return true
+	}
+	if dir&encoding != 0 && (has("MarshalCSV", nil, []string{"string", "error"}) ||
+		has("MarshalText", nil, []string{"[]byte", "error"}) ||
+		has("String", nil, []string{"string"})) {
+		return true
+	}
+	return false
+}
+
+func typeNames(tuple *types.Tuple) string {
+	names := make([]string, tuple.Len())
+	for i := range names {
+		names[i] = tuple.At(i).Type().String()
+	}
+	return strings.Join(names, ",")
+}
@@ -0,0 +1,12 @@
+package gocsvlint_test
+
+import (
+	"testing"
+
+	"github.com/gocarina/gocsv/gocsvlint"
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestAnalyzer(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), gocsvlint.Analyzer, "example.com/a", "example.com/b")
+}
@@ -0,0 +1,59 @@
+package a
+
+import (
+	"io"
+	"strings"
+	"time"
+
+	"github.com/gocarina/gocsv"
+)
+
+type Base struct {
+	ID int `csv:"id"`
+}
+
+type Client struct {
+	Base
+	Name    string    `csv:"name,omitempty"`
+	Email   string    `csv:"email|omitempty"` // want `csv tag "email\|omitempty" of field Email separates omitempty with "\|", but the TagSeparator is ","`
+	Key     int       `csv:"id"`              // want `duplicate csv key "id" in Client: field Key is ignored, ID is used`
+	secret  string    `csv:"secret"`          // want `unexported field secret of Client has a csv tag, but is ignored`
+	cache   []string  // Not mapped
+	Tags    []string  `csv:"tags"` // want `field Tags of Client: \[\]string has no conversion from a string`
+	Created time.Time `csv:"created"`
+	Status  Status    `csv:"status"`
+	Ignored chan int  `csv:"-"`
+	Age     int       `csv:"age,min=0,max=150"`
+	Score   int       `csv:"score,min=0"`
+	SKU     string    `csv:"sku,pattern=^[A-Z]{3}-\\d{1,6}$"`
+}
+
+type Status int
+
+func (s *Status) UnmarshalCSV(string) error { return nil }
+
+type Row struct {
+	Label Label `csv:"label"` // want `field Label of Row: Label has no conversion from nor to a string`
+}
+
+type Label struct {
+	Text string
+}
+
+type Printed struct {
+	Label fmtLabel `csv:"label"` // want `field Label of Printed: fmtLabel has no conversion from a string`
+}
+
+type fmtLabel struct{}
+
+func (fmtLabel) String() string { return "" }
+
+func use(in io.Reader, out io.Writer) {
+	var clients []*Client
+	gocsv.Unmarshal(in, &clients)
+	gocsv.Unmarshal(strings.NewReader(""), &clients) // Reported once
+	gocsv.MarshalTable(clients, out, gocsv.TableOptions{})
+	gocsv.SchemaOf[Row]()
+	gocsv.Marshal([]Printed{}, out)
+	gocsv.UnmarshalToCallback(in, func(p Printed) {})
+}
@@ -0,0 +1,21 @@
+package b
+
+import (
+	"io"
+
+	"github.com/gocarina/gocsv"
+)
+
+func init() {
+	gocsv.TagSeparator = "|"
+}
+
+type Client struct {
+	Name  string `csv:"name|omitempty"`
+	Email string `csv:"email,omitempty"` // want `csv tag "email,omitempty" of field Email separates omitempty with ",", but the TagSeparator is "\|"`
+}
+
+func use(in io.Reader) {
+	var clients []Client
+	gocsv.Unmarshal(in, &clients)
+}
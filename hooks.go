@@ -0,0 +1,108 @@
+package gocsv
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// CSVAfterUnmarshal is implemented by the structs that complete or normalize
+// their fields once they are decoded from a row.
+type CSVAfterUnmarshal interface {
+	AfterUnmarshalCSV() error
+}
+
+// CSVValidator is implemented by the structs that check their fields once
+// they are decoded from a row, after AfterUnmarshalCSV.
+type CSVValidator interface {
+	ValidateCSV() error
+}
+
+// CSVBeforeMarshal is implemented by the structs that normalize their fields
+// before they are encoded to a row.
+type CSVBeforeMarshal interface {
+	BeforeMarshalCSV() error
+}
+
+var (
+	afterUnmarshalType = reflect.TypeOf((*CSVAfterUnmarshal)(nil)).Elem()
+	validatorType      = reflect.TypeOf((*CSVValidator)(nil)).Elem()
+	beforeMarshalType  = reflect.TypeOf((*CSVBeforeMarshal)(nil)).Elem()
+)
+
+// RowError is returned when the hook of a row fails, or when a row to encode
+// breaks a rule of the csv tags, Line being the line of the row in the CSV,
+// the header being line 1 when there is one.
+type RowError struct {
+	Line int
+	Err  error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("row on line %d: %v", e.Line, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
+
+// decodeHooks are the hooks a struct type implements, with a pointer receiver or not.
+type decodeHooks struct {
+	afterUnmarshal bool
+	validator      bool
+}
+
+func getDecodeHooks(t reflect.Type) decodeHooks {
+	pt := reflect.PtrTo(t)
+	return decodeHooks{
+		afterUnmarshal: pt.Implements(afterUnmarshalType),
+		validator:      pt.Implements(validatorType),
+	}
+}
+
+// run calls the hooks of the decoded struct, v or the struct v points to,
+// which must be addressable.
+func (h decodeHooks) run(v reflect.Value, line int) error {
+	if !h.afterUnmarshal && !h.validator {
+		return nil
+	}
+	if v.Kind() != reflect.Ptr {
+		v = v.Addr()
+	}
+	if h.afterUnmarshal {
+		if err := v.Interface().(CSVAfterUnmarshal).AfterUnmarshalCSV(); err != nil {
+			return &RowError{Line: line, Err: err}
+		}
+	}
+	if h.validator {
+		if err := v.Interface().(CSVValidator).ValidateCSV(); err != nil {
+			return &RowError{Line: line, Err: err}
+		}
+	}
+	return nil
+}
+
+// beforeMarshal calls the BeforeMarshalCSV hook of v, a struct or a pointer
+// to a struct, if it has one. It returns the struct to encode: v, or a copy
+// of v changed by the hook when v is not addressable.
+func beforeMarshal(v reflect.Value, line int) (reflect.Value, error) {
+	s := v
+	if s.Kind() == reflect.Ptr {
+		if s.IsNil() {
+			return v, nil
+		}
+		s = s.Elem()
+	}
+	if !reflect.PtrTo(s.Type()).Implements(beforeMarshalType) {
+		return v, nil
+	}
+	if !s.CanAddr() {
+		ptr := reflect.New(s.Type())
+		ptr.Elem().Set(s)
+		s = ptr.Elem()
+		v = s
+	}
+	if err := s.Addr().Interface().(CSVBeforeMarshal).BeforeMarshalCSV(); err != nil {
+		return v, &RowError{Line: line, Err: err}
+	}
+	return v, nil
+}
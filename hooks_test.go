@@ -0,0 +1,123 @@
+package gocsv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type hookedClient struct {
+	Name  string `csv:"name"`
+	Email string `csv:"email"`
+	Age   int    `csv:"age"`
+}
+
+func (c *hookedClient) AfterUnmarshalCSV() error {
+	c.Email = strings.ToLower(strings.TrimSpace(c.Email))
+	return nil
+}
+
+func (c *hookedClient) ValidateCSV() error {
+	if c.Age < 0 {
+		return errors.New("negative age")
+	}
+	return nil
+}
+
+func (c *hookedClient) BeforeMarshalCSV() error {
+	if c.Name == "" {
+		return errors.New("missing name")
+	}
+	c.Name = strings.ToUpper(c.Name)
+	return nil
+}
+
+const hookedCSV = "name,email,age\nalice, Alice@Example.COM ,34\nbob,BOB@example.com,-1\n"
+
+func TestHooks_decode(t *testing.T) {
+	var clients []hookedClient
+	err := UnmarshalString("name,email,age\nalice, Alice@Example.COM ,34\n", &clients)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if clients[0].Email != "alice@example.com" {
+		t.Errorf("AfterUnmarshalCSV not called: got %q", clients[0].Email)
+	}
+
+	assertRowError := func(name string, err error) {
+		t.Helper()
+		var rowErr *RowError
+		if !errors.As(err, &rowErr) || rowErr.Line != 3 || rowErr.Err.Error() != "negative age" {
+			t.Errorf("%s: got %v, wanted a *RowError on line 3", name, err)
+		}
+	}
+	var pointers []*hookedClient
+	assertRowError("Unmarshal", UnmarshalString(hookedCSV, &pointers))
+	assertRowError("UnmarshalCSV", UnmarshalCSV(csv.NewReader(strings.NewReader(hookedCSV)), &pointers))
+
+	var first string
+	err = UnmarshalToCallback(strings.NewReader(hookedCSV), func(c hookedClient) {
+		if first == "" {
+			first = c.Email
+		}
+	})
+	assertRowError("UnmarshalToCallback", err)
+	if first != "alice@example.com" {
+		t.Errorf("UnmarshalToCallback: got %q", first)
+	}
+
+	err = UnmarshalBatches(strings.NewReader(hookedCSV), 10, func([]hookedClient) error { return nil })
+	assertRowError("UnmarshalBatches", err)
+
+	um, err := NewUnmarshaller(csv.NewReader(strings.NewReader(hookedCSV)), hookedClient{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := um.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := v.(hookedClient); c.Email != "alice@example.com" {
+		t.Errorf("Unmarshaller: got %q", c.Email)
+	}
+	_, err = um.Read()
+	assertRowError("Unmarshaller", err)
+}
+
+func TestHooks_encode(t *testing.T) {
+	clients := []hookedClient{{Name: "alice", Age: 34}, {Name: "bob", Age: 51}}
+	out, err := MarshalString(clients)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "name,email,age\nALICE,,34\nBOB,,51\n"; out != want {
+		t.Errorf("got %q, wanted %q", out, want)
+	}
+	if clients[0].Name != "ALICE" {
+		t.Errorf("the hook did not change the slice element: %q", clients[0].Name)
+	}
+
+	// A copy of the array elements is changed
+	array := [1]hookedClient{{Name: "carol"}}
+	if out, err = MarshalString(array); err != nil || out != "name,email,age\nCAROL,,0\n" {
+		t.Errorf("got %q, %v", out, err)
+	}
+
+	var rowErr *RowError
+	err = MarshalWithoutHeaders([]*hookedClient{{Name: "dan"}, {}}, &bytes.Buffer{})
+	if !errors.As(err, &rowErr) || rowErr.Line != 2 {
+		t.Errorf("got %v, wanted a *RowError on line 2", err)
+	}
+
+	c := make(chan interface{}, 2)
+	c <- hookedClient{Name: "eve"}
+	c <- &hookedClient{}
+	close(c)
+	var buf bytes.Buffer
+	err = MarshalChan(c, DefaultCSVWriter(&buf))
+	if !errors.As(err, &rowErr) || rowErr.Line != 3 {
+		t.Errorf("MarshalChan: got %v, wanted a *RowError on line 3", err)
+	}
+}
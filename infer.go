@@ -0,0 +1,338 @@
+package gocsv
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ColumnType is the type of the values of a CSV column, as inferred by InferSchema.
+type ColumnType int
+
+const (
+	// StringColumn holds any text, or no value at all.
+	StringColumn ColumnType = iota
+	// IntColumn holds integers, without leading zeros.
+	IntColumn
+	// FloatColumn holds numbers, some of them not integers.
+	FloatColumn
+	// BoolColumn holds booleans, like true, FALSE, yes or no.
+	BoolColumn
+	// TimeColumn holds times, all in the same layout.
+	TimeColumn
+)
+
+func (t ColumnType) String() string {
+	switch t {
+	case IntColumn:
+		return "int"
+	case FloatColumn:
+		return "float"
+	case BoolColumn:
+		return "bool"
+	case TimeColumn:
+		return "time"
+	}
+	return "string"
+}
+
+// inferLayouts are the time layouts InferSchema looks for, by order of preference.
+var inferLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"01/02/2006",
+	"02/01/2006",
+	"2006/01/02",
+	"02.01.2006",
+	"01/02/2006 15:04:05",
+	"02/01/2006 15:04:05",
+	time.RFC1123Z,
+	time.RFC1123,
+	"15:04:05",
+}
+
+// ColumnSchema is the inferred schema of a CSV column.
+type ColumnSchema struct {
+	Name string
+	Type ColumnType
+	// Layout is the time layout of the values of a TimeColumn.
+	Layout string
+	// Nullable is true if some values of the column are empty.
+	Nullable bool
+	// Values is the number of non-empty values of the column.
+	Values int
+}
+
+// Schema is the inferred schema of a CSV.
+type Schema struct {
+	Columns []ColumnSchema
+	// Rows is the number of rows read to infer the schema, header excluded.
+	Rows int
+}
+
+// columnInference is the state of the inference of a column: the types and
+// the time layouts all its values fit so far.
+type columnInference struct {
+	isInt, isFloat, isBool bool
+	layouts                []string
+}
+
+func newColumnInference() *columnInference {
+	return &columnInference{isInt: true, isFloat: true, isBool: true, layouts: inferLayouts}
+}
+
+func (c *columnInference) add(s string) {
+	if c.isInt && (hasLeadingZero(s) || !isInferredInt(s)) {
+		c.isInt = false
+	}
+	if c.isFloat && (hasLeadingZero(s) || !isNumeric(s)) {
+		c.isFloat = false
+	}
+	if c.isBool && !isInferredBool(s) {
+		c.isBool = false
+	}
+	if len(c.layouts) > 0 {
+		var layouts []string
+		for _, layout := range c.layouts {
+			if _, err := time.Parse(layout, s); err == nil {
+				layouts = append(layouts, layout)
+			}
+		}
+		c.layouts = layouts
+	}
+}
+
+// hasLeadingZero reports whether s starts with zeros that a number would lose,
+// as codes and phone numbers do.
+func hasLeadingZero(s string) bool {
+	digits := strings.TrimLeft(s, "+-")
+	return len(digits) > 1 && digits[0] == '0' && '0' <= digits[1] && digits[1] <= '9'
+}
+
+func isInferredInt(s string) bool {
+	_, err := strconv.ParseInt(s, 10, 64)
+	return err == nil
+}
+
+// isInferredBool reports whether s is a boolean as decoded by Unmarshal: yes,
+// no, or a value of strconv.ParseBool. The numbers 1 and 0 are inferred as
+// integers first.
+func isInferredBool(s string) bool {
+	if s == "yes" || s == "no" {
+		return true
+	}
+	_, err := strconv.ParseBool(s)
+	return err == nil
+}
+
+func (c *columnInference) columnType() (ColumnType, string) {
+	switch {
+	case c.isInt:
+		return IntColumn, ""
+	case c.isFloat:
+		return FloatColumn, ""
+	case c.isBool:
+		return BoolColumn, ""
+	case len(c.layouts) > 0:
+		return TimeColumn, c.layouts[0]
+	}
+	return StringColumn, ""
+}
+
+// InferSchema reads up to sampleRows rows of the CSV, all of them if
+// sampleRows is not positive, and infers the type of each column from its
+// non-empty values. A column of which all the values are empty is a
+// StringColumn.
+func InferSchema(in io.Reader, sampleRows int) (*Schema, error) {
+	reader := getCSVReader(in)
+	headers, err := reader.Read()
+	if err == io.EOF {
+		return nil, ErrEmptyCSV
+	} else if err != nil {
+		return nil, err
+	}
+	trimHeaderBOM(headers)
+	schema := &Schema{Columns: make([]ColumnSchema, len(headers))}
+	inferences := make([]*columnInference, len(headers))
+	for i, h := range headers {
+		schema.Columns[i].Name = h
+		inferences[i] = newColumnInference()
+	}
+	for sampleRows <= 0 || schema.Rows < sampleRows {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		schema.Rows++
+		for i := range schema.Columns {
+			if i >= len(record) || record[i] == "" {
+				schema.Columns[i].Nullable = true
+				continue
+			}
+			schema.Columns[i].Values++
+			inferences[i].add(record[i])
+		}
+	}
+	for i := range schema.Columns {
+		if schema.Columns[i].Values > 0 {
+			schema.Columns[i].Type, schema.Columns[i].Layout = inferences[i].columnType()
+		}
+	}
+	return schema, nil
+}
+
+// sparse reports whether at least half the values of the column are empty.
+func (c *ColumnSchema) sparse(rows int) bool {
+	return rows > 0 && 2*c.Values <= rows
+}
+
+// goType returns the Go type of the struct field of the column. Nullable
+// columns other than strings are pointers, nil for the empty values. Times
+// in a layout time.Time does not decode are strings.
+func (c *ColumnSchema) goType() string {
+	var t string
+	switch c.Type {
+	case IntColumn:
+		t = "int"
+	case FloatColumn:
+		t = "float64"
+	case BoolColumn:
+		t = "bool"
+	case TimeColumn:
+		if c.Layout != time.RFC3339Nano {
+			return "string"
+		}
+		t = "time.Time"
+	default:
+		return "string"
+	}
+	if c.Nullable {
+		return "*" + t
+	}
+	return t
+}
+
+// GenerateStruct returns the Go source of a struct type named name, with a
+// field tagged for each column of the schema. The fields of the sparse
+// columns, and the pointers of the nullable ones, are tagged omitempty.
+// When pkg is not empty, the source is a whole file of that package.
+// An error is returned for a column that no tag can match: one named with
+// the TagSeparator or a backtick, empty, "-", "omitempty", or like a rule
+// such as "min=1".
+func GenerateStruct(schema *Schema, pkg, name string) ([]byte, error) {
+	var b bytes.Buffer
+	usesTime := false
+	for i := range schema.Columns {
+		if strings.HasSuffix(schema.Columns[i].goType(), "time.Time") {
+			usesTime = true
+		}
+	}
+	if pkg != "" {
+		fmt.Fprintf(&b, "package %s\n\n", pkg)
+		if usesTime {
+			b.WriteString("import \"time\"\n\n")
+		}
+	}
+	name = goName(name, "Record")
+	fmt.Fprintf(&b, "// %s is a record of the CSV.\ntype %s struct {\n", name, name)
+	used := make(map[string]int)
+	for i := range schema.Columns {
+		c := &schema.Columns[i]
+		field := goName(c.Name, "Column"+strconv.Itoa(i+1))
+		if used[field]++; used[field] > 1 {
+			field += strconv.Itoa(used[field])
+		}
+		if err := checkTagKey(c.Name); err != nil {
+			return nil, err
+		}
+		goType := c.goType()
+		tag := c.Name
+		if c.sparse(schema.Rows) || strings.HasPrefix(goType, "*") {
+			tag += TagSeparator + "omitempty"
+		}
+		fmt.Fprintf(&b, "\t%s %s `csv:%s`", field, goType, strconv.Quote(tag))
+		if c.Type == TimeColumn && goType == "string" {
+			fmt.Fprintf(&b, " // Time, layout %q", c.Layout)
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString("}\n")
+	return format.Source(b.Bytes())
+}
+
+// checkTagKey returns an error if name, as the key of a csv tag, would not
+// match the column of that name: the field would be ignored, named after
+// the Go field, or the key parsed as an option or a rule.
+func checkTagKey(name string) error {
+	switch {
+	case strings.Contains(name, TagSeparator) || strings.Contains(name, "`"):
+		return fmt.Errorf("gocsv: column %q cannot be a csv tag, it contains %q or a backtick", name, TagSeparator)
+	case name == "" || name == "-" || name == "omitempty":
+		return fmt.Errorf("gocsv: column %q cannot be a csv tag, the tag has another meaning", name)
+	case isRuleOption(name):
+		return fmt.Errorf("gocsv: column %q cannot be a csv tag, it is a rule", name)
+	}
+	return nil
+}
+
+// goInitialisms are the words written in capitals in Go names.
+var goInitialisms = map[string]bool{
+	"API": true, "ASCII": true, "CPU": true, "CSS": true, "CSV": true, "DNS": true, "EOF": true,
+	"GUID": true, "HTML": true, "HTTP": true, "HTTPS": true, "ID": true, "IP": true, "JSON": true,
+	"SKU": true, "SQL": true, "SSN": true, "TCP": true, "TTL": true, "UDP": true, "UI": true,
+	"UID": true, "URI": true, "URL": true, "UTC": true, "UUID": true, "VAT": true, "XML": true,
+}
+
+// goName returns an exported Go identifier for s, like UserID for "user_id",
+// or fallback if s has no letter nor digit.
+func goName(s, fallback string) string {
+	var words []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = word[:0]
+		}
+	}
+	runes := []rune(s)
+	for i, r := range runes {
+		switch {
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r):
+			flush()
+		case unicode.IsUpper(r) && i > 0 && unicode.IsLower(runes[i-1]):
+			flush() // camelCase
+			word = append(word, r)
+		default:
+			word = append(word, r)
+		}
+	}
+	flush()
+	if len(words) == 0 {
+		return fallback
+	}
+	var b strings.Builder
+	for _, w := range words {
+		if upper := strings.ToUpper(w); goInitialisms[upper] {
+			b.WriteString(upper)
+			continue
+		}
+		r := []rune(w)
+		b.WriteRune(unicode.ToUpper(r[0]))
+		b.WriteString(string(r[1:]))
+	}
+	name := b.String()
+	if !unicode.IsLetter([]rune(name)[0]) {
+		name = "F" + name // Like F2fa for "2fa"
+	}
+	return name
+}
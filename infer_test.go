@@ -0,0 +1,205 @@
+package gocsv
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInferSchema(t *testing.T) {
+	in := "user_id,First Name,score,active,created,birthday,zip,note,empty\n" +
+		"1,Alice,9.5,true,2024-03-01T12:30:00Z,01/02/1990,02134,,\n" +
+		"2,Bob,7,no,2024-03-02T08:00:00+01:00,31/12/1985,75001,,\n" +
+		"3,Carol,,TRUE,2024-03-03T00:00:00Z,15/06/2001,10115,vip,\n"
+	schema, err := InferSchema(strings.NewReader(in), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if schema.Rows != 3 {
+		t.Errorf("got %d rows, wanted 3", schema.Rows)
+	}
+	want := []ColumnSchema{
+		{Name: "user_id", Type: IntColumn, Values: 3},
+		{Name: "First Name", Type: StringColumn, Values: 3},
+		{Name: "score", Type: FloatColumn, Nullable: true, Values: 2},
+		{Name: "active", Type: BoolColumn, Values: 3},
+		{Name: "created", Type: TimeColumn, Layout: time.RFC3339Nano, Values: 3},
+		{Name: "birthday", Type: TimeColumn, Layout: "02/01/2006", Values: 3},
+		{Name: "zip", Type: StringColumn, Values: 3},
+		{Name: "note", Type: StringColumn, Nullable: true, Values: 1},
+		{Name: "empty", Type: StringColumn, Nullable: true},
+	}
+	for i, c := range schema.Columns {
+		if c != want[i] {
+			t.Errorf("column %d: got %+v, wanted %+v", i, c, want[i])
+		}
+	}
+
+	schema, err = InferSchema(strings.NewReader("n\n1\nx\n"), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if schema.Rows != 1 || schema.Columns[0].Type != IntColumn {
+		t.Errorf("got %+v, wanted an int column from 1 row", schema)
+	}
+	if _, err := InferSchema(strings.NewReader(""), 0); err != ErrEmptyCSV {
+		t.Errorf("got %v, wanted ErrEmptyCSV", err)
+	}
+}
+
+func TestGenerateStruct(t *testing.T) {
+	in := "user_id,First Name,score,created,birthday,note,user-id,2fa\n" +
+		"1,Alice,9.5,2024-03-01T12:30:00Z,1990-02-01,,1,true\n" +
+		"2,Bob,,2024-03-02T08:00:00Z,1985-12-31,vip,2,false\n" +
+		"3,Carol,7,2024-03-03T00:00:00Z,2001-06-15,,3,false\n"
+	schema, err := InferSchema(strings.NewReader(in), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := GenerateStruct(schema, "feeds", "client record")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "package feeds\n\n" +
+		"import \"time\"\n\n" +
+		"// ClientRecord is a record of the CSV.\n" +
+		"type ClientRecord struct {\n" +
+		"\tUserID    int       `csv:\"user_id\"`\n" +
+		"\tFirstName string    `csv:\"First Name\"`\n" +
+		"\tScore     *float64  `csv:\"score,omitempty\"`\n" +
+		"\tCreated   time.Time `csv:\"created\"`\n" +
+		"\tBirthday  string    `csv:\"birthday\"` // Time, layout \"2006-01-02\"\n" +
+		"\tNote      string    `csv:\"note,omitempty\"`\n" +
+		"\tUserID2   int       `csv:\"user-id\"`\n" +
+		"\tF2fa      bool      `csv:\"2fa\"`\n" +
+		"}\n"
+	if string(src) != want {
+		t.Errorf("got\n%s\nwanted\n%s", src, want)
+	}
+
+	// The generated struct decodes the CSV
+	type ClientRecord struct {
+		UserID    int       `csv:"user_id"`
+		FirstName string    `csv:"First Name"`
+		Score     *float64  `csv:"score,omitempty"`
+		Created   time.Time `csv:"created"`
+		Birthday  string    `csv:"birthday"`
+		Note      string    `csv:"note,omitempty"`
+		UserID2   int       `csv:"user-id"`
+		F2fa      bool      `csv:"2fa"`
+	}
+	var records []ClientRecord
+	if err := UnmarshalString(in, &records); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 || records[1].Score != nil || *records[2].Score != 7 || records[1].Note != "vip" {
+		t.Errorf("got %+v", records)
+	}
+}
+
+func TestInferSchema_bools(t *testing.T) {
+	in := "a,b,c\ntrue,yes,YES\nF,no,No\nTrue,yes,yes\n"
+	schema, err := InferSchema(strings.NewReader(in), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, want := range []ColumnType{BoolColumn, BoolColumn, StringColumn} {
+		if got := schema.Columns[i].Type; got != want {
+			t.Errorf("column %s: got %v, wanted %v", schema.Columns[i].Name, got, want)
+		}
+	}
+	src, err := GenerateStruct(schema, "", "flags")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "// Flags is a record of the CSV.\n" +
+		"type Flags struct {\n" +
+		"\tA bool   `csv:\"a\"`\n" +
+		"\tB bool   `csv:\"b\"`\n" +
+		"\tC string `csv:\"c\"`\n" +
+		"}\n"
+	if string(src) != want {
+		t.Errorf("got\n%s\nwanted\n%s", src, want)
+	}
+
+	// The generated struct decodes the sample it was inferred from
+	type Flags struct {
+		A bool   `csv:"a"`
+		B bool   `csv:"b"`
+		C string `csv:"c"`
+	}
+	var records []Flags
+	if err := UnmarshalString(in, &records); err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 || !records[0].A || records[1].A || !records[2].B || records[1].C != "No" {
+		t.Errorf("got %+v", records)
+	}
+}
+
+func TestGenerateStruct_invalidTag(t *testing.T) {
+	names := []string{"a,b", "a`b", "", "-", "omitempty",
+		"min=1", "max=1", "minlen=1", "maxlen=1", "oneof=a", "pattern=a"}
+	for _, name := range names {
+		schema := &Schema{Columns: []ColumnSchema{{Name: name}}}
+		if _, err := GenerateStruct(schema, "", "record"); err == nil {
+			t.Errorf("%q: got no error", name)
+		}
+	}
+
+	// A field tagged with the name of its column does not decode it, but for
+	// the separator and the backtick, which no tag can hold
+	for _, name := range names[2:] {
+		if decodesTagged(name) {
+			t.Errorf("%q: decoded by a field tagged with it, it should be accepted", name)
+		}
+	}
+	for _, name := range []string{"min", "pattern", "-a", "omitempty2", "x=1"} {
+		schema := &Schema{Columns: []ColumnSchema{{Name: name}}}
+		if _, err := GenerateStruct(schema, "", "record"); err != nil {
+			t.Errorf("%q: got %v", name, err)
+		}
+		if !decodesTagged(name) {
+			t.Errorf("%q: not decoded by a field tagged with it", name)
+		}
+	}
+}
+
+// decodesTagged reports whether a string field tagged as GenerateStruct does
+// for the column name decodes it.
+func decodesTagged(name string) bool {
+	t := reflect.StructOf([]reflect.StructField{{
+		Name: "A",
+		Type: reflect.TypeOf(""),
+		Tag:  reflect.StructTag("csv:" + strconv.Quote(name)),
+	}})
+	out := reflect.New(reflect.SliceOf(t))
+	in := strconv.Quote(name) + "\nv\n"
+	if err := UnmarshalString(in, out.Interface()); err != nil {
+		return false
+	}
+	rows := out.Elem()
+	return rows.Len() == 1 && rows.Index(0).Field(0).String() == "v"
+}
+
+func TestGoName(t *testing.T) {
+	tests := map[string]string{
+		"user_id":      "UserID",
+		"First Name":   "FirstName",
+		"createdAt":    "CreatedAt",
+		"HTTP status":  "HTTPStatus",
+		"prix (€)":     "Prix",
+		"2fa":          "F2fa",
+		"éte":          "Éte",
+		"  ":           "Fallback",
+		"api-url":      "APIURL",
+		"already_Good": "AlreadyGood",
+	}
+	for in, want := range tests {
+		if got := goName(in, "Fallback"); got != want {
+			t.Errorf("goName(%q): got %q, wanted %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,487 @@
+package gocsv
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+)
+
+// JSONOption configures the conversions between CSV and JSON.
+type JSONOption func(*jsonOptions)
+
+type jsonOptions struct {
+	structType reflect.Type
+}
+
+// JSONTypesFrom makes the conversions follow the types of the fields of the
+// struct, or pointer to struct, v. The columns of the numbers and bools are
+// checked and written as such, the others as strings. Without it, CSVToJSONL
+// writes the values that look like JSON numbers and bools as such, and
+// JSONLToCSV takes its columns from the first object.
+func JSONTypesFrom(v interface{}) JSONOption {
+	return func(o *jsonOptions) {
+		t := reflect.TypeOf(v)
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		o.structType = t
+	}
+}
+
+func newJSONOptions(opts []JSONOption) (*jsonOptions, error) {
+	o := &jsonOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.structType != nil {
+		if err := ensureOutInnerType(o.structType); err != nil {
+			return nil, err
+		}
+	}
+	return o, nil
+}
+
+// CSVToJSONL converts the CSV from in into JSON lines in out, one object per
+// row, with the keys in the order of the columns. Dotted column names, like
+// "address.city", make nested objects.
+func CSVToJSONL(in io.Reader, out io.Writer, opts ...JSONOption) error {
+	return csvToJSON(in, out, false, opts)
+}
+
+// CSVToJSON converts the CSV from in into a JSON array in out, as CSVToJSONL.
+func CSVToJSON(in io.Reader, out io.Writer, opts ...JSONOption) error {
+	return csvToJSON(in, out, true, opts)
+}
+
+// jsonKind is how the values of a column are written in JSON.
+type jsonKind int
+
+const (
+	jsonInferred jsonKind = iota
+	jsonString
+	jsonNumber
+	jsonBool
+)
+
+// jsonNode is a key of the objects written by csvToJSON: a column, or an
+// object of the columns with a common dotted prefix.
+type jsonNode struct {
+	key      string
+	col      int // Column of the value, -1 for an object
+	children []*jsonNode
+}
+
+func (n *jsonNode) child(key string) *jsonNode {
+	for _, c := range n.children {
+		if c.key == key {
+			return c
+		}
+	}
+	return nil
+}
+
+// newJSONTree returns the object of the columns of headers.
+func newJSONTree(headers []string) (*jsonNode, error) {
+	root := &jsonNode{col: -1}
+	for col, header := range headers {
+		node := root
+		keys := strings.Split(header, ".")
+		for i, key := range keys {
+			c := node.child(key)
+			last := i == len(keys)-1
+			switch {
+			case c == nil:
+				c = &jsonNode{key: key, col: -1}
+				if last {
+					c.col = col
+				}
+				node.children = append(node.children, c)
+			case last || c.col >= 0:
+				return nil, fmt.Errorf("column %q conflicts with another column", header)
+			}
+			node = c
+		}
+	}
+	return root, nil
+}
+
+// jsonColumn converts the values of a column for csvToJSON.
+type jsonColumn struct {
+	kind  jsonKind
+	field reflect.Value // Zero value of the struct field of the column, if any
+}
+
+func csvToJSON(in io.Reader, out io.Writer, array bool, opts []JSONOption) error {
+	o, err := newJSONOptions(opts)
+	if err != nil {
+		return err
+	}
+	decoder := newDecoder(in)
+	headers, err := decoder.getCSVRow()
+	if err == io.EOF {
+		return ErrEmptyCSV
+	} else if err != nil {
+		return err
+	}
+	trimHeaderBOM(headers)
+	tree, err := newJSONTree(headers)
+	if err != nil {
+		return err
+	}
+	columns := make([]jsonColumn, len(headers))
+	if o.structType != nil {
+		info := getStructInfo(o.structType)
+		for i, header := range headers {
+			if f := getCSVFieldPosition(header, info, 0); f != nil {
+				t := o.structType.FieldByIndex(f.IndexChain).Type
+				columns[i] = jsonColumn{kind: jsonKindOf(t), field: reflect.New(t).Elem()}
+			}
+		}
+	}
+
+	w := bufio.NewWriter(out)
+	var buf []byte
+	if array {
+		w.WriteByte('[')
+	}
+	i := 0
+	for ; ; i++ {
+		row, err := decoder.getCSVRow()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		buf = buf[:0]
+		if array && i > 0 {
+			buf = append(buf, ',')
+		}
+		if array || i > 0 {
+			buf = append(buf, '\n')
+		}
+		if buf, err = appendJSONObject(buf, tree, row, columns, i); err != nil {
+			return err
+		}
+		if _, err := w.Write(buf); err != nil {
+			return err
+		}
+	}
+	if array {
+		w.WriteString("\n]")
+	}
+	if array || i > 0 {
+		w.WriteByte('\n')
+	}
+	return w.Flush()
+}
+
+// jsonKindOf returns how the values of a struct field of type t are written.
+func jsonKindOf(t reflect.Type) jsonKind {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	p := reflect.PointerTo(t)
+	if p.Implements(marshallerType) || p.Implements(textMarshalerType) || p.Implements(stringerType) {
+		return jsonString
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return jsonBool
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return jsonNumber
+	}
+	return jsonString
+}
+
+// appendJSONObject appends the object of the node for the i-th row of the CSV body.
+func appendJSONObject(buf []byte, node *jsonNode, row []string, columns []jsonColumn, i int) ([]byte, error) {
+	buf = append(buf, '{')
+	for n, c := range node.children {
+		if n > 0 {
+			buf = append(buf, ',')
+		}
+		buf = appendJSONString(buf, c.key)
+		buf = append(buf, ':')
+		var err error
+		if c.col < 0 {
+			buf, err = appendJSONObject(buf, c, row, columns, i)
+		} else if c.col >= len(row) {
+			buf = append(buf, "null"...)
+		} else if buf, err = columns[c.col].appendValue(buf, row[c.col]); err != nil {
+			err = &csv.ParseError{Line: i + 2, Column: c.col + 1, Err: err}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return append(buf, '}'), nil
+}
+
+func (c *jsonColumn) appendValue(buf []byte, value string) ([]byte, error) {
+	if c.kind == jsonInferred {
+		if value == "true" || value == "false" || isJSONNumber(value) {
+			return append(buf, value...), nil
+		}
+		return appendJSONString(buf, value), nil
+	}
+	if value == "" && c.field.Kind() == reflect.Ptr {
+		return append(buf, "null"...), nil
+	}
+	c.field.Set(reflect.Zero(c.field.Type()))
+	if err := setField(c.field, value, false); err != nil {
+		return nil, err
+	}
+	s, err := getFieldAsString(c.field)
+	if err != nil {
+		return nil, err
+	}
+	switch c.kind {
+	case jsonString:
+		return appendJSONString(buf, s), nil
+	case jsonNumber:
+		if f := reflect.Indirect(c.field); f.CanFloat() && (math.IsNaN(f.Float()) || math.IsInf(f.Float(), 0)) {
+			return append(buf, "null"...), nil
+		}
+	}
+	return append(buf, s...), nil
+}
+
+func appendJSONString(buf []byte, s string) []byte {
+	b, _ := json.Marshal(s)
+	return append(buf, b...)
+}
+
+// isJSONNumber reports whether s is a number in the JSON grammar: no sign
+// but '-', no leading zeros, no trailing dot.
+func isJSONNumber(s string) bool {
+	i := 0
+	if i < len(s) && s[i] == '-' {
+		i++
+	}
+	digits := func() int {
+		start := i
+		for i < len(s) && '0' <= s[i] && s[i] <= '9' {
+			i++
+		}
+		return i - start
+	}
+	if i < len(s) && s[i] == '0' {
+		i++
+	} else if digits() == 0 {
+		return false
+	}
+	if i < len(s) && s[i] == '.' {
+		i++
+		if digits() == 0 {
+			return false
+		}
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		i++
+		if i < len(s) && (s[i] == '+' || s[i] == '-') {
+			i++
+		}
+		if digits() == 0 {
+			return false
+		}
+	}
+	return i == len(s)
+}
+
+// JSONLToCSV converts the JSON objects from in, as JSON lines or as a JSON
+// array, into CSV in out. Nested objects are written in dotted columns, like
+// "address.city", and arrays as JSON text. The columns are those of the
+// first object, in order, unless JSONTypesFrom gives a struct. A key with no
+// column is an error, a missing key an empty value.
+func JSONLToCSV(in io.Reader, out io.Writer, opts ...JSONOption) error {
+	o, err := newJSONOptions(opts)
+	if err != nil {
+		return err
+	}
+	next, err := newJSONObjectReader(in)
+	if err != nil {
+		return err
+	}
+	writer := getCSVWriter(out)
+
+	var headers []string
+	var fields []reflect.Value // Zero values of the struct fields of the columns
+	columns := make(map[string]int)
+	if o.structType != nil {
+		info := getStructInfo(o.structType)
+		for i, f := range info.Fields {
+			headers = append(headers, f.getFirstKey())
+			fields = append(fields, reflect.New(o.structType.FieldByIndex(f.IndexChain).Type).Elem())
+			columns[f.getFirstKey()] = i
+		}
+	}
+
+	var row []string
+	for n := 1; ; n++ {
+		raw, err := next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("object %d: %v", n, err)
+		}
+		if headers == nil && o.structType == nil {
+			if err := flattenJSON(raw, "", func(key string, _ json.RawMessage) error {
+				columns[key] = len(headers)
+				headers = append(headers, key)
+				return nil
+			}); err != nil {
+				return fmt.Errorf("object %d: %v", n, err)
+			}
+		}
+		if row == nil {
+			if err := writer.Write(headers); err != nil {
+				return err
+			}
+			row = make([]string, len(headers))
+		}
+		for i := range row {
+			row[i] = ""
+		}
+		err = flattenJSON(raw, "", func(key string, value json.RawMessage) error {
+			col, ok := columns[key]
+			if !ok {
+				return fmt.Errorf("no column for key %q", key)
+			}
+			s, err := jsonToCSVValue(value)
+			if err != nil {
+				return fmt.Errorf("key %q: %v", key, err)
+			}
+			if fields != nil {
+				if s, err = normalizeValue(fields[col], s); err != nil {
+					return fmt.Errorf("key %q: %v", key, err)
+				}
+			}
+			row[col] = s
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("object %d: %v", n, err)
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	if row == nil && headers != nil { // No objects, write the header of the struct
+		if err := writer.Write(headers); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// newJSONObjectReader returns a func reading the next JSON value from in, as
+// JSON lines or as the elements of a JSON array. It returns io.EOF at the end.
+func newJSONObjectReader(in io.Reader) (func() (json.RawMessage, error), error) {
+	r := bufio.NewReader(in)
+	for {
+		c, err := r.Peek(1)
+		if err == io.EOF {
+			return func() (json.RawMessage, error) { return nil, io.EOF }, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if c[0] != ' ' && c[0] != '\t' && c[0] != '\r' && c[0] != '\n' {
+			break
+		}
+		r.ReadByte()
+	}
+	dec := json.NewDecoder(r)
+	array := false
+	if c, _ := r.Peek(1); c[0] == '[' {
+		if _, err := dec.Token(); err != nil {
+			return nil, err
+		}
+		array = true
+	}
+	return func() (json.RawMessage, error) {
+		if array && !dec.More() {
+			if _, err := dec.Token(); err != nil { // The closing bracket
+				return nil, err
+			}
+			return nil, io.EOF
+		}
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			return nil, err
+		}
+		return raw, nil
+	}, nil
+}
+
+var errNotJSONObject = errors.New("not a JSON object")
+
+// flattenJSON calls f with the dotted key and the value of each non-object
+// value of the JSON object raw, in order.
+func flattenJSON(raw json.RawMessage, prefix string, f func(key string, value json.RawMessage) error) error {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || raw[0] != '{' {
+		if prefix == "" {
+			return errNotJSONObject
+		}
+		return f(prefix, raw)
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if _, err := dec.Token(); err != nil {
+		return err
+	}
+	for dec.More() {
+		token, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		key := token.(string)
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return err
+		}
+		if err := flattenJSON(value, key, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// jsonToCSVValue returns the CSV value of the JSON value raw.
+func jsonToCSVValue(raw json.RawMessage) (string, error) {
+	switch raw[0] {
+	case 'n':
+		return "", nil
+	case '"':
+		var s string
+		err := json.Unmarshal(raw, &s)
+		return s, err
+	case '[':
+		var b bytes.Buffer
+		err := json.Compact(&b, raw)
+		return b.String(), err
+	}
+	return string(raw), nil
+}
+
+// normalizeValue returns the value as written by the encoder for the type
+// of field, or the error of its conversion.
+func normalizeValue(field reflect.Value, value string) (string, error) {
+	field.Set(reflect.Zero(field.Type()))
+	if err := setField(field, value, false); err != nil {
+		return "", err
+	}
+	return getFieldAsString(field)
+}
@@ -0,0 +1,110 @@
+package gocsv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type jsonSample struct {
+	ID      int      `csv:"id"`
+	Zip     string   `csv:"address.zip"`
+	City    string   `csv:"address.city"`
+	Active  bool     `csv:"active"`
+	Balance *float64 `csv:"balance"`
+}
+
+func TestCSVToJSONL(t *testing.T) {
+	in := "id,address.zip,address.city,active,balance\n1,007,Paris,true,1.5\n2,10,\"Lyon, \"\"FR\"\"\",false,\n"
+	b := bytes.Buffer{}
+	if err := CSVToJSONL(strings.NewReader(in), &b); err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"id":1,"address":{"zip":"007","city":"Paris"},"active":true,"balance":1.5}` + "\n" +
+		`{"id":2,"address":{"zip":10,"city":"Lyon, \"FR\""},"active":false,"balance":""}` + "\n"
+	if b.String() != expected {
+		t.Fatalf("expected\n%s, got\n%s", expected, b.String())
+	}
+
+	b.Reset()
+	if err := CSVToJSON(strings.NewReader(in), &b, JSONTypesFrom(jsonSample{})); err != nil {
+		t.Fatal(err)
+	}
+	expected = "[\n" +
+		`{"id":1,"address":{"zip":"007","city":"Paris"},"active":true,"balance":1.5},` + "\n" +
+		`{"id":2,"address":{"zip":"10","city":"Lyon, \"FR\""},"active":false,"balance":null}` + "\n]\n"
+	if b.String() != expected {
+		t.Fatalf("expected\n%s, got\n%s", expected, b.String())
+	}
+}
+
+func TestCSVToJSONL_errors(t *testing.T) {
+	err := CSVToJSONL(strings.NewReader("id,active\n1,true\nx,false\n"), &bytes.Buffer{}, JSONTypesFrom(&jsonSample{}))
+	var parseErr *csv.ParseError
+	if !errors.As(err, &parseErr) || parseErr.Line != 3 || parseErr.Column != 1 {
+		t.Fatalf("expected an error on line 3, column 1, got %v", err)
+	}
+	if err := CSVToJSONL(strings.NewReader("a,a.b\n1,2\n"), &bytes.Buffer{}); err == nil {
+		t.Fatal("expected an error for conflicting columns")
+	}
+}
+
+func TestJSONLToCSV(t *testing.T) {
+	in := `{"id":1,"address":{"zip":"007","city":"Paris"},"active":true,"tags":["a","b"]}
+{"active":false,"id":2,"address":{"city":"Lyon, \"FR\""},"tags":null}
+`
+	b := bytes.Buffer{}
+	if err := JSONLToCSV(strings.NewReader(in), &b); err != nil {
+		t.Fatal(err)
+	}
+	expected := "id,address.zip,address.city,active,tags\n" +
+		"1,007,Paris,true,\"[\"\"a\"\",\"\"b\"\"]\"\n" +
+		"2,,\"Lyon, \"\"FR\"\"\",false,\n"
+	if b.String() != expected {
+		t.Fatalf("expected\n%s, got\n%s", expected, b.String())
+	}
+
+	// A JSON array, with the columns of a struct.
+	in = `[{"id":1,"address":{"zip":"007"},"balance":2.50}, {"id":2}]`
+	b.Reset()
+	if err := JSONLToCSV(strings.NewReader(in), &b, JSONTypesFrom(jsonSample{})); err != nil {
+		t.Fatal(err)
+	}
+	expected = "id,address.zip,address.city,active,balance\n" +
+		"1,007,,,2.5\n" +
+		"2,,,,\n"
+	if b.String() != expected {
+		t.Fatalf("expected\n%s, got\n%s", expected, b.String())
+	}
+
+	// Round trip.
+	var samples []jsonSample
+	if err := Unmarshal(&b, &samples); err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 2 || samples[0].Zip != "007" || *samples[0].Balance != 2.5 {
+		t.Fatalf("unexpected samples %+v", samples)
+	}
+}
+
+func TestJSONLToCSV_errors(t *testing.T) {
+	tests := []struct {
+		in, err string
+	}{
+		{`{"a":1}` + "\n" + `{"b":2}`, `object 2: no column for key "b"`},
+		{`{"a":1} 3`, "object 2: not a JSON object"},
+		{`{"id":"x"}`, `object 1: key "id": `},
+	}
+	for _, test := range tests {
+		var opts []JSONOption
+		if strings.Contains(test.in, "id") {
+			opts = append(opts, JSONTypesFrom(jsonSample{}))
+		}
+		err := JSONLToCSV(strings.NewReader(test.in), &bytes.Buffer{}, opts...)
+		if err == nil || !strings.HasPrefix(err.Error(), test.err) {
+			t.Errorf("%s: expected error %q, got %v", test.in, test.err, err)
+		}
+	}
+}
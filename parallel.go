@@ -0,0 +1,238 @@
+package gocsv
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"runtime"
+	"sync"
+)
+
+// ParallelOptions configures the parallel decoding functions.
+type ParallelOptions struct {
+	// Workers is the number of goroutines converting rows into structs.
+	// Zero means runtime.GOMAXPROCS(0).
+	Workers int
+	// BatchSize is the number of rows handed to a worker at once.
+	// Zero means 256.
+	BatchSize int
+}
+
+const defaultParallelBatchSize = 256
+
+func (o ParallelOptions) workers() int {
+	if o.Workers > 0 {
+		return o.Workers
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+func (o ParallelOptions) batchSize() int {
+	if o.BatchSize > 0 {
+		return o.BatchSize
+	}
+	return defaultParallelBatchSize
+}
+
+// UnmarshalParallel parses the CSV from the reader in the interface, converting
+// the rows on several goroutines. The rows keep their order in the container,
+// and errors are reported as with Unmarshal.
+func UnmarshalParallel(in io.Reader, out interface{}, opts ParallelOptions) error {
+	return readToParallel(newDecoder(in), out, opts)
+}
+
+// UnmarshalParallelToChan parses the CSV from the reader and send each value in the chan c,
+// converting the rows on several goroutines. The values are sent in the order of the rows.
+// The channel must have a concrete type.
+func UnmarshalParallelToChan(in io.Reader, c interface{}, opts ParallelOptions) error {
+	if c == nil {
+		return fmt.Errorf("goscv: channel is %v", c)
+	}
+	return readEachParallel(newDecoder(in), c, opts)
+}
+
+// UnmarshalParallelToCallback parses the CSV from the reader and send each value to the given func f,
+// converting the rows on several goroutines. f is called from a single goroutine, in the order of the rows.
+// The func must look like func(Struct).
+func UnmarshalParallelToCallback(in io.Reader, f interface{}, opts ParallelOptions) error {
+	valueFunc := reflect.ValueOf(f)
+	t := reflect.TypeOf(f)
+	if t.NumIn() != 1 {
+		return fmt.Errorf("the given function must have exactly one parameter")
+	}
+	outInnerType := t.In(0)
+	outInnerWasPointer := outInnerType.Kind() == reflect.Ptr
+	if outInnerWasPointer {
+		outInnerType = outInnerType.Elem()
+	}
+	if err := ensureOutInnerType(outInnerType); err != nil {
+		return err
+	}
+	return decodeParallel(newDecoder(in), outInnerWasPointer, outInnerType, opts, func(v reflect.Value) error {
+		valueFunc.Call([]reflect.Value{v})
+		return nil
+	})
+}
+
+func readToParallel(decoder SimpleDecoder, out interface{}, opts ParallelOptions) error {
+	outValue, outType := getConcreteReflectValueAndType(out) // Get the concrete type (not pointer) (Slice<?> or Array<?>)
+	if err := ensureOutType(outType); err != nil {
+		return err
+	}
+	outInnerWasPointer, outInnerType := getConcreteContainerInnerType(outType) // Get the concrete inner type (not pointer) (Container<"?">)
+	if err := ensureOutInnerType(outInnerType); err != nil {
+		return err
+	}
+	var values []reflect.Value
+	err := decodeParallel(decoder, outInnerWasPointer, outInnerType, opts, func(v reflect.Value) error {
+		values = append(values, v)
+		return nil
+	})
+	if err == io.EOF {
+		return ErrEmptyCSV
+	} else if err != nil {
+		return err
+	}
+	if err := ensureOutCapacity(&outValue, len(values)+1); err != nil { // Ensure the container is big enough to hold the CSV content
+		return err
+	}
+	for i, v := range values {
+		outValue.Index(i).Set(v)
+	}
+	return nil
+}
+
+func readEachParallel(decoder SimpleDecoder, c interface{}, opts ParallelOptions) error {
+	outValue, outType := getConcreteReflectValueAndType(c) // Get the concrete type (not pointer) (Slice<?> or Array<?>)
+	if err := ensureOutType(outType); err != nil {
+		return err
+	}
+	defer outValue.Close()
+	outInnerWasPointer, outInnerType := getConcreteContainerInnerType(outType) // Get the concrete inner type (not pointer) (Container<"?">)
+	if err := ensureOutInnerType(outInnerType); err != nil {
+		return err
+	}
+	return decodeParallel(decoder, outInnerWasPointer, outInnerType, opts, func(v reflect.Value) error {
+		outValue.Send(v)
+		return nil
+	})
+}
+
+// rowBatch is a run of consecutive CSV rows. first is the index of the first
+// row in the CSV body, err is the read error that ended the input, if any.
+type rowBatch struct {
+	seq    int
+	first  int
+	rows   [][]string
+	values []reflect.Value
+	err    error
+}
+
+// decodeParallel reads the header and the rows from decoder on one goroutine,
+// converts batches of rows on opts.Workers goroutines, and calls emit with each
+// value in the order of the rows. It stops at the first error in row order, so
+// the values emitted and the error returned are the same as with readEach.
+func decodeParallel(decoder SimpleDecoder, outInnerWasPointer bool, outInnerType reflect.Type, opts ParallelOptions, emit func(reflect.Value) error) error {
+	headers, err := decoder.getCSVRow()
+	if err != nil {
+		return err
+	}
+	rd, err := newRowDecoder(outInnerWasPointer, outInnerType, headers, nil)
+	if err != nil {
+		return err
+	}
+
+	workers, batchSize := opts.workers(), opts.batchSize()
+	// The goroutines are stopped, then joined, before returning: none of
+	// them uses the decoder once decodeParallel returns.
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	done := make(chan struct{})
+	defer close(done)
+	tokens := make(chan struct{}, 2*workers) // Bounds the batches read but not yet emitted
+	batches := make(chan *rowBatch)
+	results := make(chan *rowBatch)
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(batches)
+		seq, first := 0, 0
+		for {
+			select {
+			case tokens <- struct{}{}:
+			case <-done:
+				return
+			}
+			batch := &rowBatch{seq: seq, first: first, rows: make([][]string, 0, batchSize)}
+			for len(batch.rows) < batchSize {
+				row, err := decoder.getCSVRow()
+				if err != nil {
+					if err != io.EOF {
+						batch.err = err
+					}
+					break
+				}
+				batch.rows = append(batch.rows, append([]string(nil), row...)) // The reader can reuse its records
+			}
+			last := batch.err != nil || len(batch.rows) < batchSize
+			select {
+			case batches <- batch:
+			case <-done:
+				return
+			}
+			if last {
+				return
+			}
+			seq++
+			first += len(batch.rows)
+		}
+	}()
+
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				batch.values = make([]reflect.Value, 0, len(batch.rows))
+				for i, row := range batch.rows {
+					v, err := rd.decodeRow(row, batch.first+i)
+					if err != nil {
+						batch.err = err
+						break
+					}
+					batch.values = append(batch.values, v)
+				}
+				select {
+				case results <- batch:
+				case <-done:
+					return
+				}
+			}
+		}()
+	}
+
+	pending := make(map[int]*rowBatch)
+	for next := 0; ; {
+		batch, ok := pending[next]
+		if !ok {
+			batch = <-results
+			pending[batch.seq] = batch
+			continue
+		}
+		delete(pending, next)
+		for _, v := range batch.values {
+			if err := emit(v); err != nil {
+				return err
+			}
+		}
+		if batch.err != nil {
+			return batch.err
+		}
+		if len(batch.rows) < batchSize {
+			return nil
+		}
+		<-tokens
+		next++
+	}
+}
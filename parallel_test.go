@@ -0,0 +1,146 @@
+package gocsv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+	"reflect"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func parallelTestCSV(rows int, badRow int) []byte {
+	b := bytes.NewBufferString("Baz,BAR\n")
+	for i := 0; i < rows; i++ {
+		bar := strconv.Itoa(i)
+		if i == badRow {
+			bar = "BAD_INPUT"
+		}
+		b.WriteString("v" + strconv.Itoa(i) + "," + bar + "\n")
+	}
+	return b.Bytes()
+}
+
+func TestUnmarshalParallel(t *testing.T) {
+	in := parallelTestCSV(1000, -1)
+	var expected []MultiTagSample
+	if err := UnmarshalBytes(in, &expected); err != nil {
+		t.Fatal(err)
+	}
+	for _, batchSize := range []int{1, 7, 1000, 5000} {
+		var samples []*MultiTagSample
+		if err := UnmarshalParallel(bytes.NewReader(in), &samples, ParallelOptions{Workers: 4, BatchSize: batchSize}); err != nil {
+			t.Fatal(err)
+		}
+		if len(samples) != len(expected) {
+			t.Fatalf("batch size %d: expected %d samples, got %d", batchSize, len(expected), len(samples))
+		}
+		for i := range expected {
+			if *samples[i] != expected[i] {
+				t.Fatalf("batch size %d: expected sample %d to be %v, got %v", batchSize, i, expected[i], *samples[i])
+			}
+		}
+	}
+
+	var samples []MultiTagSample
+	if err := UnmarshalParallel(bytes.NewReader(nil), &samples, ParallelOptions{}); err != ErrEmptyCSV {
+		t.Fatalf("expected ErrEmptyCSV, got %v", err)
+	}
+}
+
+func TestUnmarshalParallel_rowError(t *testing.T) {
+	in := parallelTestCSV(1000, 613)
+	var samples []MultiTagSample
+	expected := UnmarshalBytes(in, &samples)
+	if expected == nil {
+		t.Fatal("expected an error from bad input")
+	}
+	err := UnmarshalParallel(bytes.NewReader(in), &samples, ParallelOptions{Workers: 8, BatchSize: 10})
+	if !reflect.DeepEqual(expected, err) {
+		t.Fatalf("expected error %v, got %v", expected, err)
+	}
+	if perr, ok := err.(*csv.ParseError); !ok || perr.Line != 615 || perr.Column != 2 {
+		t.Fatalf("expected csv.ParseError on line 615, column 2, got %v", err)
+	}
+
+	c := make(chan MultiTagSample)
+	cerr := make(chan error, 1)
+	go func() {
+		cerr <- UnmarshalParallelToChan(bytes.NewReader(in), c, ParallelOptions{Workers: 8, BatchSize: 10})
+	}()
+	n := 0
+	for range c {
+		n++
+	}
+	if n != 613 {
+		t.Fatalf("expected the 613 rows before the bad one, got %d", n)
+	}
+	if err := <-cerr; !reflect.DeepEqual(expected, err) {
+		t.Fatalf("expected error %v, got %v", expected, err)
+	}
+}
+
+// countingReader counts the calls to Read, and returns a few bytes at a time.
+type countingReader struct {
+	r     io.Reader
+	reads int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	atomic.AddInt64(&c.reads, 1)
+	if len(p) > 16 {
+		p = p[:16]
+	}
+	return c.r.Read(p)
+}
+
+func TestUnmarshalParallel_stopsReading(t *testing.T) {
+	in := &countingReader{r: bytes.NewReader(parallelTestCSV(10000, 3))}
+	var samples []MultiTagSample
+	if err := UnmarshalParallel(in, &samples, ParallelOptions{Workers: 2, BatchSize: 10}); err == nil {
+		t.Fatal("expected an error from bad input")
+	}
+	reads := atomic.LoadInt64(&in.reads)
+	time.Sleep(10 * time.Millisecond)
+	if n := atomic.LoadInt64(&in.reads); n != reads {
+		t.Errorf("the input was read %d times after UnmarshalParallel returned", n-reads)
+	}
+}
+
+func TestUnmarshalParallel_reuseRecord(t *testing.T) {
+	defer SetCSVReader(selfCSVReader)
+	SetCSVReader(func(in io.Reader) CSVReader {
+		r := NewReader(in)
+		r.ReuseRecord = true
+		return r
+	})
+	in := parallelTestCSV(1000, -1)
+	var samples []MultiTagSample
+	if err := UnmarshalParallel(bytes.NewReader(in), &samples, ParallelOptions{Workers: 4, BatchSize: 7}); err != nil {
+		t.Fatal(err)
+	}
+	for i, s := range samples {
+		if s.Bar != i {
+			t.Fatalf("sample %d: got %+v", i, s)
+		}
+	}
+}
+
+func TestUnmarshalParallelToCallback(t *testing.T) {
+	in := parallelTestCSV(500, -1)
+	i := 0
+	err := UnmarshalParallelToCallback(bytes.NewReader(in), func(s *MultiTagSample) {
+		if s.Bar != i {
+			t.Fatalf("expected row %d, got %d", i, s.Bar)
+		}
+		i++
+	}, ParallelOptions{Workers: 3, BatchSize: 16})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if i != 500 {
+		t.Fatalf("expected 500 calls, got %d", i)
+	}
+}
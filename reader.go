@@ -0,0 +1,456 @@
+package gocsv
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxInternedValues bounds the number of distinct values interned per column.
+const maxInternedValues = 1024
+
+// Reader is a CSV reader implementing CSVReader. It reads the same format as
+// csv.Reader, and reports errors as *csv.ParseError too, but reuses its buffers
+// from one record to the next.
+//
+// When the decoder reads from a Reader, it parses numbers and booleans straight
+// from the bytes returned by ReadBytes, without allocating strings.
+// The decoder uses it when set by SetCSVReader, the default being csv.Reader.
+type Reader struct {
+	// Comma, Comment, FieldsPerRecord, LazyQuotes and TrimLeadingSpace have
+	// the same meaning as in csv.Reader.
+	Comma            rune
+	Comment          rune
+	FieldsPerRecord  int
+	LazyQuotes       bool
+	TrimLeadingSpace bool
+
+	// ReuseRecord makes Read return the same slice from one call to the next.
+	// The strings it holds stay valid. Do not set it with UnmarshalContext,
+	// which keeps the records it reads.
+	ReuseRecord bool
+
+	// InternStrings makes Read, and the decoder, reuse the same string for
+	// the values repeated in a column, up to maxInternedValues per column.
+	// It saves memory and allocations for low-cardinality columns.
+	InternStrings bool
+
+	r *bufio.Reader
+
+	// dialect is set by NewDialectReader, commaDialect is derived from Comma otherwise.
+	dialect           *dialect
+	dialectErr        error
+	commaDialect      *dialect
+	commaDialectComma rune
+
+	numLine int
+	// crLine tells that the last line read ended with a "\r" RecordTerminator,
+	// turned into "\n" by readLine.
+	crLine bool
+
+	// rawBuffer holds a line that did not fit in the bufio.Reader.
+	rawBuffer []byte
+	// recordBuffer holds the unescaped fields of the current record, one after another.
+	recordBuffer []byte
+	// fieldIndexes holds the end of each field in recordBuffer.
+	fieldIndexes []int
+	// fieldPositions holds the start of each field of the current record.
+	fieldPositions []position
+	// fields holds the fields of the current record, returned by ReadBytes.
+	fields [][]byte
+	// record is the slice returned by Read when ReuseRecord is set.
+	record []string
+	// interned holds the interned values of each column.
+	interned []map[string]string
+}
+
+// NewReader returns a new Reader that reads from in.
+func NewReader(in io.Reader) *Reader {
+	return &Reader{
+		Comma: ',',
+		r:     bufio.NewReader(in),
+	}
+}
+
+// NewDialectReader returns a new Reader that reads from in the CSV format
+// described by d. Its Comma is ignored. Read fails if d is not valid.
+func NewDialectReader(in io.Reader, d Dialect) *Reader {
+	r := NewReader(in)
+	r.dialect, r.dialectErr = d.compile()
+	return r
+}
+
+// Read reads one record from r.
+func (r *Reader) Read() (record []string, err error) {
+	ok, err := r.readRecord()
+	if !ok {
+		return nil, err
+	}
+	if r.ReuseRecord {
+		record = r.record[:0]
+	}
+	if cap(record) < len(r.fieldIndexes) {
+		record = make([]string, len(r.fieldIndexes))
+	}
+	record = record[:len(r.fieldIndexes)]
+	if r.InternStrings {
+		for i := range record {
+			record[i] = r.fieldString(i, r.field(i))
+		}
+	} else {
+		// A single string for the whole record, as csv.Reader does.
+		str := string(r.recordBuffer)
+		preIdx := 0
+		for i, idx := range r.fieldIndexes {
+			record[i] = str[preIdx:idx]
+			preIdx = idx
+		}
+	}
+	if r.ReuseRecord {
+		r.record = record
+	}
+	return record, err
+}
+
+// ReadBytes reads one record from r, and returns its fields as byte slices.
+// They are only valid until the next call to Read or ReadBytes.
+func (r *Reader) ReadBytes() (fields [][]byte, err error) {
+	ok, err := r.readRecord()
+	if !ok {
+		return nil, err
+	}
+	r.fields = r.fields[:0]
+	for i := range r.fieldIndexes {
+		r.fields = append(r.fields, r.field(i))
+	}
+	return r.fields, err
+}
+
+// ReadAll reads all the remaining records from r. The records are never reused.
+func (r *Reader) ReadAll() (records [][]string, err error) {
+	reuse := r.ReuseRecord
+	r.ReuseRecord = false
+	defer func() { r.ReuseRecord = reuse }()
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+}
+
+func (r *Reader) field(i int) []byte {
+	start := 0
+	if i > 0 {
+		start = r.fieldIndexes[i-1]
+	}
+	return r.recordBuffer[start:r.fieldIndexes[i]]
+}
+
+// fieldString returns the field of the column col as a string, interned if
+// InternStrings is set.
+func (r *Reader) fieldString(col int, b []byte) string {
+	if !r.InternStrings {
+		return string(b)
+	}
+	for len(r.interned) <= col {
+		r.interned = append(r.interned, make(map[string]string))
+	}
+	m := r.interned[col]
+	if s, ok := m[string(b)]; ok {
+		return s
+	}
+	s := string(b)
+	if len(m) < maxInternedValues {
+		m[s] = s
+	}
+	return s
+}
+
+// position is the line and column where a field starts.
+type position struct {
+	line, col int
+}
+
+// FieldPos returns the line and column of the start of the field with the
+// given index in the record most recently read, as csv.Reader does. Both
+// start at 1, and columns are counted in bytes. It panics for an index out
+// of bounds.
+func (r *Reader) FieldPos(field int) (line, column int) {
+	if field < 0 || field >= len(r.fieldIndexes) {
+		panic("out of range index passed to FieldPos")
+	}
+	p := r.fieldPositions[field]
+	return p.line, p.col
+}
+
+// readLine reads the next line, with its line ending normalized to \n.
+// The result is only valid until the next call.
+func (r *Reader) readLine(lineEnd byte) ([]byte, error) {
+	line, err := r.r.ReadSlice(lineEnd)
+	if err == bufio.ErrBufferFull {
+		r.rawBuffer = append(r.rawBuffer[:0], line...)
+		for err == bufio.ErrBufferFull {
+			line, err = r.r.ReadSlice(lineEnd)
+			r.rawBuffer = append(r.rawBuffer, line...)
+		}
+		line = r.rawBuffer
+	}
+	if len(line) > 0 && err == io.EOF {
+		err = nil
+		// For backwards compatibility, drop trailing \r before EOF.
+		if line[len(line)-1] == '\r' {
+			line = line[:len(line)-1]
+		}
+	}
+	r.numLine++
+	if lineEnd != '\n' {
+		r.crLine = false
+		if n := len(line); n > 0 && line[n-1] == lineEnd {
+			line[n-1] = '\n'
+			r.crLine = true
+		}
+		return line, err
+	}
+	// Normalize \r\n to \n on all input lines.
+	if n := len(line); n >= 2 && line[n-2] == '\r' && line[n-1] == '\n' {
+		line[n-2] = '\n'
+		line = line[:n-1]
+	}
+	return line, err
+}
+
+// lengthNL reports the number of bytes for the trailing \n.
+func lengthNL(b []byte) int {
+	if len(b) > 0 && b[len(b)-1] == '\n' {
+		return 1
+	}
+	return 0
+}
+
+// nextRune returns the next rune in b or utf8.RuneError.
+func nextRune(b []byte) rune {
+	r, _ := utf8.DecodeRune(b)
+	return r
+}
+
+// getDialect returns the dialect the Reader was created with, or the one
+// described by its Comma.
+func (r *Reader) getDialect() (*dialect, error) {
+	if r.dialect != nil || r.dialectErr != nil {
+		return r.dialect, r.dialectErr
+	}
+	if r.commaDialect == nil || r.commaDialectComma != r.Comma {
+		if r.Comma == r.Comment || !validDelim(r.Comma) || (r.Comment != 0 && !validDelim(r.Comment)) {
+			return nil, errInvalidDelim
+		}
+		d := DefaultDialect()
+		d.Delimiter = string(r.Comma)
+		compiled, err := d.compile()
+		if err != nil {
+			return nil, errInvalidDelim
+		}
+		r.commaDialect, r.commaDialectComma = compiled, r.Comma
+	}
+	return r.commaDialect, nil
+}
+
+// readRecord reads the next record into recordBuffer and fieldIndexes. Like
+// csv.Reader, it keeps the fields read before a parse error; ok reports whether
+// there is a record, possibly partial, to return.
+func (r *Reader) readRecord() (ok bool, err error) {
+	d, err := r.getDialect()
+	if err != nil {
+		return false, err
+	}
+
+	// Read line (automatically skipping past empty lines and any comments).
+	var line []byte
+	var errRead error
+	for errRead == nil {
+		line, errRead = r.readLine(d.lineEnd)
+		if r.Comment != 0 && nextRune(line) == r.Comment {
+			line = nil
+			continue // Skip comment lines
+		}
+		if errRead == nil && len(line) == lengthNL(line) {
+			line = nil
+			continue // Skip empty lines
+		}
+		break
+	}
+	r.recordBuffer = r.recordBuffer[:0]
+	r.fieldIndexes = r.fieldIndexes[:0]
+	r.fieldPositions = r.fieldPositions[:0]
+	if errRead == io.EOF {
+		return false, errRead
+	}
+
+	// Parse each field in the record.
+	recLine := r.numLine // Starting line for record
+	fullLine := line
+	col := func() int { return len(fullLine) - len(line) + 1 }
+	// eofLine and eofCol are the end of the last line, where csv.Reader
+	// reports a quoted field ended by the end of the input.
+	var eofLine, eofCol int
+	nextLine := func() {
+		eofLine, eofCol = r.numLine, len(fullLine)+1
+		line, errRead = r.readLine(d.lineEnd)
+		fullLine = line
+		if errRead == io.EOF {
+			errRead = nil
+		}
+	}
+	// escaped appends the character following an escape character, which
+	// starts line, and reports whether there was one.
+	escaped := func() bool {
+		line = line[len(d.escape):]
+		if len(line) == 0 {
+			return false
+		}
+		_, size := utf8.DecodeRune(line)
+		r.recordBuffer = append(r.recordBuffer, line[:size]...)
+		line = line[size:]
+		if len(line) == 0 && errRead == nil {
+			nextLine() // The escaped character was the line ending
+		}
+		return true
+	}
+parseField:
+	for {
+		if r.TrimLeadingSpace {
+			line = bytes.TrimLeftFunc(line, unicode.IsSpace)
+		}
+		r.fieldPositions = append(r.fieldPositions, position{line: r.numLine, col: col()})
+		if !bytes.HasPrefix(line, d.quote) {
+			// Non-quoted string field
+			for {
+				i := bytes.Index(line, d.delim)
+				field := line
+				if i >= 0 {
+					field = field[:i]
+				} else {
+					field = field[:len(field)-lengthNL(field)]
+				}
+				k := -1
+				if d.escape != nil {
+					k = bytes.Index(field, d.escape)
+				}
+				if k >= 0 {
+					field = field[:k]
+				}
+				// Check to make sure a quote does not appear in field.
+				if !r.LazyQuotes {
+					if j := bytes.Index(field, d.quote); j >= 0 {
+						err = &csv.ParseError{StartLine: recLine, Line: r.numLine, Column: col() + j, Err: csv.ErrBareQuote}
+						break parseField
+					}
+				}
+				r.recordBuffer = append(r.recordBuffer, field...)
+				if k >= 0 {
+					line = line[k:]
+					if escaped() {
+						continue
+					}
+				}
+				r.fieldIndexes = append(r.fieldIndexes, len(r.recordBuffer))
+				if i >= 0 && k < 0 {
+					line = line[i+len(d.delim):]
+					continue parseField
+				}
+				break parseField
+			}
+		}
+
+		// Quoted string field
+		line = line[len(d.quote):]
+		for {
+			i := bytes.Index(line, d.quote)
+			if d.escape != nil {
+				if k := bytes.Index(line, d.escape); k >= 0 && (i < 0 || k < i) {
+					// Hit an escape character.
+					r.recordBuffer = append(r.recordBuffer, line[:k]...)
+					line = line[k:]
+					escaped()
+					continue
+				}
+			}
+			if i >= 0 {
+				// Hit next quote.
+				r.recordBuffer = append(r.recordBuffer, line[:i]...)
+				line = line[i+len(d.quote):]
+				switch {
+				case d.doubleQuote && bytes.HasPrefix(line, d.quote):
+					// `""` sequence (append quote).
+					r.recordBuffer = append(r.recordBuffer, d.quote...)
+					line = line[len(d.quote):]
+				case bytes.HasPrefix(line, d.delim):
+					// `",` sequence (end of field).
+					line = line[len(d.delim):]
+					r.fieldIndexes = append(r.fieldIndexes, len(r.recordBuffer))
+					continue parseField
+				case lengthNL(line) == len(line):
+					// `"\n` sequence (end of line).
+					r.fieldIndexes = append(r.fieldIndexes, len(r.recordBuffer))
+					break parseField
+				case r.LazyQuotes:
+					// `"` sequence (bare quote).
+					r.recordBuffer = append(r.recordBuffer, d.quote...)
+				default:
+					// `"*` sequence (invalid non-escaped quote).
+					err = &csv.ParseError{StartLine: recLine, Line: r.numLine, Column: col() - len(d.quote), Err: csv.ErrQuote}
+					break parseField
+				}
+			} else if len(line) > 0 {
+				// Hit end of line (copy all data so far).
+				r.recordBuffer = append(r.recordBuffer, line...)
+				if r.crLine && line[len(line)-1] == '\n' {
+					r.recordBuffer[len(r.recordBuffer)-1] = '\r' // The line ending is part of the field
+				}
+				if errRead != nil {
+					break parseField
+				}
+				nextLine()
+			} else {
+				// Abrupt end of file (EOF or error).
+				if !r.LazyQuotes && errRead == nil {
+					errLine, errCol := r.numLine, col()
+					if len(fullLine) == 0 {
+						errLine, errCol = eofLine, eofCol
+					}
+					err = &csv.ParseError{StartLine: recLine, Line: errLine, Column: errCol, Err: csv.ErrQuote}
+					break parseField
+				}
+				r.fieldIndexes = append(r.fieldIndexes, len(r.recordBuffer))
+				break parseField
+			}
+		}
+	}
+	if err == nil {
+		err = errRead
+	}
+
+	// Check or update the expected fields per record.
+	if r.FieldsPerRecord > 0 {
+		if len(r.fieldIndexes) != r.FieldsPerRecord && err == nil {
+			err = &csv.ParseError{StartLine: recLine, Line: recLine, Column: 1, Err: csv.ErrFieldCount}
+		}
+	} else if r.FieldsPerRecord == 0 {
+		r.FieldsPerRecord = len(r.fieldIndexes)
+	}
+	return true, err
+}
+
+var errInvalidDelim = errors.New("csv: invalid field or comment delimiter")
+
+func validDelim(r rune) bool {
+	return r != 0 && r != '"' && r != '\r' && r != '\n' && utf8.ValidRune(r) && r != utf8.RuneError
+}
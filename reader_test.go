@@ -0,0 +1,271 @@
+package gocsv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+var readerTests = []struct {
+	Name             string
+	Input            string
+	Comma            rune
+	Comment          rune
+	FieldsPerRecord  int
+	LazyQuotes       bool
+	TrimLeadingSpace bool
+}{
+	{Name: "Simple", Input: "a,b,c\n"},
+	{Name: "CRLF", Input: "a,b\r\nc,d\r\n"},
+	{Name: "NoEOLTest", Input: "a,b,c"},
+	{Name: "Semicolon", Input: "a;b;c\n", Comma: ';'},
+	{Name: "MultiLine", Input: "\"two\nline\",\"one line\",\"three\nline\nfield\"\n"},
+	{Name: "BlankLine", Input: "a,b,c\n\nd,e,f\n\n"},
+	{Name: "TrimSpace", Input: " a,  b,   c\n", TrimLeadingSpace: true},
+	{Name: "LeadingSpace", Input: " a,  b,   c\n"},
+	{Name: "Comment", Input: "#1,2,3\na,b,c\n#comment", Comment: '#'},
+	{Name: "NoComment", Input: "#1,2,3\na,b,c"},
+	{Name: "LazyQuotes", Input: `a "word","1"2",a","b`, LazyQuotes: true},
+	{Name: "BareQuotes", Input: `a "word","1"2",a"`, LazyQuotes: true},
+	{Name: "BareDoubleQuotes", Input: `a""b,c`, LazyQuotes: true},
+	{Name: "BadDoubleQuotes", Input: `a""b,c`},
+	{Name: "TrimQuote", Input: ` "a"," b",c`, TrimLeadingSpace: true},
+	{Name: "BadBareQuote", Input: `a "word","b"`},
+	{Name: "BadTrailingQuote", Input: `"a word",b"`},
+	{Name: "ExtraneousQuote", Input: `"a "word","b"`},
+	{Name: "BadFieldCount", Input: "a,b,c\nd,e"},
+	{Name: "BadFieldCount1", Input: `a,b,c`, FieldsPerRecord: 2},
+	{Name: "FieldCount", Input: "a,b,c\nd,e", FieldsPerRecord: -1},
+	{Name: "TrailingCommaEOF", Input: "a,b,c,"},
+	{Name: "TrailingCommaEOL", Input: "a,b,c,\n"},
+	{Name: "TrailingCommaSpaceEOF", Input: "a,b,c, ", TrimLeadingSpace: true},
+	{Name: "LeadingCommaSpaceEOF", Input: ",,", TrimLeadingSpace: true},
+	{Name: "TrailingCommaLine3", Input: "a,b,c\nd,e,f\ng,hi,", TrimLeadingSpace: true},
+	{Name: "NotTrailingComma3", Input: "a,b,c, \n"},
+	{Name: "DoubleQuoteWithTrailingCRLF", Input: "\"foo\"\"bar\"\r\n"},
+	{Name: "EvenQuotes", Input: `""""""""`},
+	{Name: "OddQuotes", Input: `"""""""`},
+	{Name: "LazyOddQuotes", Input: `"""""""`, LazyQuotes: true},
+	{Name: "BadComma", Input: "a\"b,c", Comma: '"'},
+	{Name: "MultiByteComma", Input: "a§b§c\n", Comma: '§'},
+	{Name: "CRLFInQuotedField", Input: "A,\"Hello\r\nHi\",B\r\n"},
+	{Name: "BinaryBlobField", Input: "x09\x41\xb4\x1c,aktau"},
+	{Name: "TrailingCR", Input: "field1,field2\r"},
+	{Name: "QuotedTrailingCR", Input: "\"field\"\r"},
+	{Name: "QuotedTrailingCRCR", Input: "\"field\"\r\r"},
+	{Name: "FieldCR", Input: "field\rfield\r"},
+	{Name: "FieldCRCRLF", Input: "field\r\r\nfield\r\r\n"},
+	{Name: "LongLine", Input: strings.Repeat("x", 10000) + ",\"" + strings.Repeat("y", 10000) + "\"\n"},
+}
+
+// TestReader checks that Reader reads the same records as csv.Reader,
+// and fails on the same inputs.
+func TestReader(t *testing.T) {
+	for _, tt := range readerTests {
+		t.Run(tt.Name, func(t *testing.T) {
+			configure := func(comma *rune, comment *rune, fieldsPerRecord *int, lazyQuotes *bool, trimLeadingSpace *bool) {
+				if tt.Comma != 0 {
+					*comma = tt.Comma
+				}
+				*comment = tt.Comment
+				*fieldsPerRecord = tt.FieldsPerRecord
+				*lazyQuotes = tt.LazyQuotes
+				*trimLeadingSpace = tt.TrimLeadingSpace
+			}
+			expected := csv.NewReader(strings.NewReader(tt.Input))
+			configure(&expected.Comma, &expected.Comment, &expected.FieldsPerRecord, &expected.LazyQuotes, &expected.TrimLeadingSpace)
+			actual := NewReader(strings.NewReader(tt.Input))
+			configure(&actual.Comma, &actual.Comment, &actual.FieldsPerRecord, &actual.LazyQuotes, &actual.TrimLeadingSpace)
+			for {
+				expectedRecord, expectedErr := expected.Read()
+				actualRecord, actualErr := actual.Read()
+				if !reflect.DeepEqual(expectedRecord, actualRecord) {
+					t.Fatalf("expected record %q, got %q", expectedRecord, actualRecord)
+				}
+				if (expectedErr == nil) != (actualErr == nil) {
+					t.Fatalf("expected error %v, got %v", expectedErr, actualErr)
+				}
+				if expectedErr != nil {
+					if perr, ok := expectedErr.(*csv.ParseError); ok {
+						if aerr, ok := actualErr.(*csv.ParseError); !ok || aerr.Err != perr.Err || aerr.Line != perr.Line {
+							t.Fatalf("expected error %v, got %v", expectedErr, actualErr)
+						}
+					}
+					if perr, ok := expectedErr.(*csv.ParseError); !ok || perr.Err != csv.ErrFieldCount {
+						break
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestReader_ReadBytes(t *testing.T) {
+	r := NewReader(strings.NewReader("a,\"b\"\"c\"\n1,2\n"))
+	r.ReuseRecord = true
+	fields, err := r.ReadBytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fields) != 2 || string(fields[0]) != "a" || string(fields[1]) != `b"c` {
+		t.Fatalf("unexpected fields %q", fields)
+	}
+	first, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertLine(t, []string{"1", "2"}, first)
+	if _, err := r.ReadBytes(); err != io.EOF {
+		t.Fatalf("expected io.EOF, got %v", err)
+	}
+}
+
+func TestReader_FieldPos(t *testing.T) {
+	in := "a,\"b\nc\",d\r\n\n  e, \"f\",\"\"\"g\"\"\"\n# comment\nh,,i\n"
+	r := NewReader(strings.NewReader(in))
+	r.Comment, r.TrimLeadingSpace = '#', true
+	std := csv.NewReader(strings.NewReader(in))
+	std.Comment, std.TrimLeadingSpace = '#', true
+	for {
+		record, err := r.Read()
+		expected, stdErr := std.Read()
+		if err != stdErr {
+			t.Fatalf("got error %v, wanted %v", err, stdErr)
+		} else if err == io.EOF {
+			break
+		}
+		assertLine(t, expected, record)
+		for i := range record {
+			line, col := r.FieldPos(i)
+			expectedLine, expectedCol := std.FieldPos(i)
+			if line != expectedLine || col != expectedCol {
+				t.Errorf("field %q: got %d:%d, wanted %d:%d", record[i], line, col, expectedLine, expectedCol)
+			}
+		}
+	}
+}
+
+func TestReader_unterminatedQuote(t *testing.T) {
+	for _, in := range []string{"\"abc", "a,\"b\nc", "x\n\"y\n", "a\r\n\"b\r\n", "a,\"\"\"b\n\nc\n"} {
+		_, err := NewReader(strings.NewReader(in)).ReadAll()
+		_, expected := csv.NewReader(strings.NewReader(in)).ReadAll()
+		if !reflect.DeepEqual(err, expected) {
+			t.Errorf("%q: got %v, wanted %v", in, err, expected)
+		}
+	}
+}
+
+func TestReader_InternStrings(t *testing.T) {
+	r := NewReader(strings.NewReader("active,1\nactive,2\n"))
+	r.InternStrings = true
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0][0] != "active" || records[1][1] != "2" {
+		t.Fatalf("unexpected records %q", records)
+	}
+	if unsafeStringData(records[0][0]) != unsafeStringData(records[1][0]) {
+		t.Fatal("expected the repeated value to be interned")
+	}
+}
+
+type benchmarkSample struct {
+	ID     int     `csv:"id"`
+	Name   string  `csv:"name"`
+	Status string  `csv:"status"`
+	Score  float64 `csv:"score"`
+	Count  uint    `csv:"count"`
+	Active bool    `csv:"active"`
+}
+
+func benchmarkCSV() []byte {
+	b := bytes.NewBufferString("id,name,status,score,count,active\n")
+	for i := 0; i < 10000; i++ {
+		b.WriteString(strconv.Itoa(i) + ",name " + strconv.Itoa(i) + ",active," + strconv.Itoa(i) + ".5," + strconv.Itoa(i*3) + ",true\n")
+	}
+	return b.Bytes()
+}
+
+func benchmarkUnmarshal(b *testing.B, reader func(io.Reader) CSVReader) {
+	in := benchmarkCSV()
+	SetCSVReader(reader)
+	defer SetCSVReader(DefaultCSVReader)
+	b.SetBytes(int64(len(in)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		var samples []benchmarkSample
+		if err := UnmarshalBytes(in, &samples); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkUnmarshal_csvReader(b *testing.B) {
+	benchmarkUnmarshal(b, func(in io.Reader) CSVReader {
+		return csv.NewReader(in)
+	})
+}
+
+func BenchmarkUnmarshal_Reader(b *testing.B) {
+	benchmarkUnmarshal(b, func(in io.Reader) CSVReader {
+		return NewReader(in)
+	})
+}
+
+func BenchmarkUnmarshal_ReaderInternStrings(b *testing.B) {
+	benchmarkUnmarshal(b, func(in io.Reader) CSVReader {
+		r := NewReader(in)
+		r.InternStrings = true
+		return r
+	})
+}
+
+func benchmarkRead(b *testing.B, read func(io.Reader) error) {
+	in := benchmarkCSV()
+	b.SetBytes(int64(len(in)))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		if err := read(bytes.NewReader(in)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkRead_csvReader(b *testing.B) {
+	benchmarkRead(b, func(in io.Reader) error {
+		r := csv.NewReader(in)
+		r.ReuseRecord = true
+		for {
+			if _, err := r.Read(); err == io.EOF {
+				return nil
+			} else if err != nil {
+				return err
+			}
+		}
+	})
+}
+
+func BenchmarkRead_ReaderReadBytes(b *testing.B) {
+	benchmarkRead(b, func(in io.Reader) error {
+		r := NewReader(in)
+		for {
+			if _, err := r.ReadBytes(); err == io.EOF {
+				return nil
+			} else if err != nil {
+				return err
+			}
+		}
+	})
+}
+
+func unsafeStringData(s string) *byte {
+	return unsafe.StringData(s)
+}
@@ -1,6 +1,7 @@
 package gocsv
 
 import (
+	"fmt"
 	"reflect"
 	"strings"
 	"sync"
@@ -19,6 +20,12 @@ type structInfo struct {
 type fieldInfo struct {
 	keys       []string
 	omitEmpty  bool
+	pointer    bool   // The field is a pointer, which an empty value leaves nil
+	quote      bool   // Always quoted when written, from a `quote:"always"` tag
+	fixed      string // Position in the fixed-width format, from the `fixed` tag
+	schema     string // Table Schema constraints, from the `schema` tag
+	rules      []fieldRule
+	rulesErr   error // Set when a rule of the csv tag is invalid
 	IndexChain []int
 }
 
@@ -64,15 +71,37 @@ func getFieldInfos(rType reflect.Type, parentIndexChain []int) []fieldInfo {
 			fieldsList = append(fieldsList, getFieldInfos(field.Type, indexChain)...)
 			continue
 		}
-		fieldInfo := fieldInfo{IndexChain: indexChain}
+		fieldInfo := fieldInfo{
+			IndexChain: indexChain,
+			pointer:    field.Type.Kind() == reflect.Ptr,
+			quote:      field.Tag.Get("quote") == "always",
+			fixed:      field.Tag.Get("fixed"),
+			schema:     field.Tag.Get("schema"),
+		}
 		fieldTag := field.Tag.Get("csv")
 		fieldTags := strings.Split(fieldTag, TagSeparator)
 		filteredTags := []string{}
-		for _, fieldTagEntry := range fieldTags {
-			if fieldTagEntry != "omitempty" {
-				filteredTags = append(filteredTags, fieldTagEntry)
-			} else {
+		for k := 0; k < len(fieldTags); k++ {
+			fieldTagEntry := fieldTags[k]
+			if strings.HasPrefix(fieldTagEntry, "pattern=") {
+				// The pattern is the rest of the tag, separators included
+				fieldTagEntry = strings.Join(fieldTags[k:], TagSeparator)
+				k = len(fieldTags)
+			}
+			switch {
+			case fieldTagEntry == "omitempty":
 				fieldInfo.omitEmpty = true
+			case isRuleOption(fieldTagEntry):
+				rule, err := parseFieldRule(fieldTagEntry)
+				if err != nil {
+					if fieldInfo.rulesErr == nil {
+						fieldInfo.rulesErr = fmt.Errorf("field %s: invalid csv tag option %q: %v", field.Name, fieldTagEntry, err)
+					}
+					continue
+				}
+				fieldInfo.rules = append(fieldInfo.rules, rule)
+			default:
+				filteredTags = append(filteredTags, fieldTagEntry)
 			}
 		}
 
@@ -88,6 +117,44 @@ func getFieldInfos(rType reflect.Type, parentIndexChain []int) []fieldInfo {
 	return fieldsList
 }
 
+// quotedColumns returns which columns must always be quoted, nil if none.
+func (s *structInfo) quotedColumns() []bool {
+	var cols []bool
+	for i, f := range s.Fields {
+		if f.quote {
+			if cols == nil {
+				cols = make([]bool, len(s.Fields))
+			}
+			cols[i] = true
+		}
+	}
+	return cols
+}
+
+// columnKinds returns the kinds of the fields of t, the struct type of s, for
+// the Writer to tell the numeric columns. Fields converted by a method, or of
+// which the kind does not tell the text, are reflect.Invalid.
+func (s *structInfo) columnKinds(t reflect.Type) []reflect.Kind {
+	kinds := make([]reflect.Kind, len(s.Fields))
+	for i, f := range s.Fields {
+		kinds[i] = f.kind(t)
+	}
+	return kinds
+}
+
+// kind returns the kind of the field in t, see columnKinds.
+func (f fieldInfo) kind(t reflect.Type) reflect.Kind {
+	ft := t.FieldByIndex(f.IndexChain).Type
+	if ft.Kind() == reflect.Ptr {
+		ft = ft.Elem()
+	}
+	pt := reflect.PtrTo(ft)
+	if pt.Implements(marshallerType) || pt.Implements(stringerType) || pt.Implements(textMarshalerType) {
+		return reflect.Invalid
+	}
+	return ft.Kind()
+}
+
 func getConcreteContainerInnerType(in reflect.Type) (inInnerWasPointer bool, inInnerType reflect.Type) {
 	inInnerType = in.Elem()
 	inInnerWasPointer = false
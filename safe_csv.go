@@ -3,12 +3,26 @@ package gocsv
 //Wraps around SafeCSVWriter and makes it thread safe.
 import (
 	"encoding/csv"
+	"fmt"
+	"io"
+	"reflect"
 	"sync"
 )
 
+// SafeCSVWriter is a CSV writer safe for concurrent use. When it wraps
+// another CSVWriter than a csv.Writer, through NewSafeWriter, the embedded
+// csv.Writer writes nowhere: its fields, Comma and UseCRLF, are ignored.
 type SafeCSVWriter struct {
 	*csv.Writer
-	m sync.Mutex
+	// Output is the output of the csv.Writer, when known. A csv.Writer
+	// cannot force quotes: with an Output, the rows of the structs tagged
+	// `quote:"always"` are written to it by a Writer in the csv.Writer's
+	// format, and without one the tags are ignored. DefaultCSVWriter sets it.
+	Output io.Writer
+
+	m           sync.Mutex
+	writer      CSVWriter // Used instead of the csv.Writer when not nil
+	quoteWriter *Writer   // Writer on Output, holding the rows written last when not nil
 }
 
 func NewSafeCSVWriter(original *csv.Writer) *SafeCSVWriter {
@@ -17,16 +31,269 @@ func NewSafeCSVWriter(original *csv.Writer) *SafeCSVWriter {
 	}
 }
 
+// NewSafeWriter wraps any CSVWriter, such as a Writer. Unless original is a
+// csv.Writer, the fields of the embedded csv.Writer are then ignored.
+func NewSafeWriter(original CSVWriter) *SafeCSVWriter {
+	if w, ok := original.(*csv.Writer); ok {
+		return NewSafeCSVWriter(w)
+	}
+	return &SafeCSVWriter{
+		Writer: csv.NewWriter(io.Discard), // Not used
+		writer: original,
+	}
+}
+
 //Override write
 func (w *SafeCSVWriter) Write(row []string) error {
 	w.m.Lock()
 	defer w.m.Unlock()
+	if w.writer != nil {
+		return w.writer.Write(row)
+	}
+	if err := w.endQuoted(); err != nil {
+		return err
+	}
 	return w.Writer.Write(row)
 }
 
+// WriteAll writes multiple CSV records using Write and then calls Flush,
+// returning any error from the Flush.
+func (w *SafeCSVWriter) WriteAll(records [][]string) error {
+	for _, record := range records {
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
 //Override flush
 func (w *SafeCSVWriter) Flush() {
 	w.m.Lock()
-	w.Writer.Flush()
+	if w.writer != nil {
+		w.writer.Flush()
+	} else if w.quoteWriter != nil {
+		w.quoteWriter.Flush()
+	} else {
+		w.Writer.Flush()
+	}
 	w.m.Unlock()
 }
+
+//Override error
+func (w *SafeCSVWriter) Error() error {
+	w.m.Lock()
+	defer w.m.Unlock()
+	if w.writer != nil {
+		return w.writer.Error()
+	}
+	if w.quoteWriter != nil {
+		if err := w.quoteWriter.Error(); err != nil {
+			return err
+		}
+	}
+	return w.Writer.Error()
+}
+
+// columnPolicy is how to write the columns of the rows of a struct: which
+// are always quoted, nil if none, and the kinds of their values.
+type columnPolicy struct {
+	quoted []bool
+	kinds  []reflect.Kind
+}
+
+// writeColumns writes row with the columns of p. The policy only holds for
+// that row, so concurrent writes of other structs are safe.
+func (w *SafeCSVWriter) writeColumns(row []string, p columnPolicy) error {
+	w.m.Lock()
+	defer w.m.Unlock()
+	if w.writer == nil {
+		if p.quoted == nil || w.Output == nil {
+			if err := w.endQuoted(); err != nil {
+				return err
+			}
+			return w.Writer.Write(row) // The quote tags are ignored without an Output
+		}
+		if w.quoteWriter == nil {
+			// The rows before, buffered by the csv.Writer, come first
+			w.Writer.Flush()
+			if err := w.Writer.Error(); err != nil {
+				return err
+			}
+			d := DefaultDialect()
+			d.Delimiter = string(w.Comma)
+			if w.UseCRLF {
+				d.RecordTerminator = "\r\n"
+			}
+			w.quoteWriter = NewDialectWriter(w.Output, d)
+		}
+		return w.quoteWriter.writeColumns(row, p)
+	}
+	if qw, ok := w.writer.(*Writer); ok {
+		return qw.writeColumns(row, p)
+	}
+	return w.writer.Write(row)
+}
+
+// endQuoted flushes the rows written to Output by the quoteWriter, before
+// the csv.Writer writes again.
+func (w *SafeCSVWriter) endQuoted() error {
+	if w.quoteWriter == nil {
+		return nil
+	}
+	w.quoteWriter.Flush()
+	err := w.quoteWriter.Error()
+	w.quoteWriter = nil
+	return err
+}
+
+// rowSink writes rows to a SafeCSVWriter, flushing it every flushEvery rows,
+// and remembers the first error so that it can be returned to every caller.
+type rowSink struct {
+	writer     *SafeCSVWriter
+	flushEvery int
+	pending    int
+	err        error
+}
+
+func (s *rowSink) write(row []string) error {
+	if s.err != nil {
+		return s.err
+	}
+	if err := s.writer.Write(row); err != nil {
+		s.err = err
+		return err
+	}
+	s.pending++
+	if s.flushEvery > 0 && s.pending >= s.flushEvery {
+		return s.flush()
+	}
+	return nil
+}
+
+func (s *rowSink) flush() error {
+	if s.err != nil {
+		return s.err
+	}
+	s.pending = 0
+	s.writer.Flush()
+	s.err = s.writer.Error()
+	return s.err
+}
+
+// RowWriter lets several goroutines write rows to one SafeCSVWriter. Each row
+// is written atomically, and the writer is flushed every flushEvery rows (only
+// by Close if flushEvery is 0). Write blocks while another row is being written.
+// Once a write fails, every Write and Close returns that first error.
+type RowWriter struct {
+	m    sync.Mutex
+	sink rowSink
+}
+
+// NewRowWriter creates a RowWriter writing to w.
+func NewRowWriter(w *SafeCSVWriter, flushEvery int) *RowWriter {
+	return &RowWriter{sink: rowSink{writer: w, flushEvery: flushEvery}}
+}
+
+// Write writes row.
+func (rw *RowWriter) Write(row []string) error {
+	rw.m.Lock()
+	defer rw.m.Unlock()
+	return rw.sink.write(row)
+}
+
+// Close flushes the rows written so far.
+func (rw *RowWriter) Close() error {
+	rw.m.Lock()
+	defer rw.m.Unlock()
+	return rw.sink.flush()
+}
+
+// OrderedRowWriter lets several goroutines write numbered rows to one
+// SafeCSVWriter, which receives them in the order of their sequence numbers,
+// starting at 0. Rows arriving early are kept in a reorder buffer; Write blocks
+// while the row is window or more rows ahead of the next one to write.
+// The writer is flushed every flushEvery rows (only by Close if flushEvery is 0).
+// Once a write fails, every Write and Close returns that first error.
+type OrderedRowWriter struct {
+	m       sync.Mutex
+	cond    *sync.Cond
+	sink    rowSink
+	window  int
+	next    int
+	pending map[int][]string
+}
+
+// NewOrderedRowWriter creates an OrderedRowWriter writing to w, buffering at most window rows.
+func NewOrderedRowWriter(w *SafeCSVWriter, window int, flushEvery int) *OrderedRowWriter {
+	if window < 1 {
+		window = 1
+	}
+	ow := &OrderedRowWriter{
+		sink:    rowSink{writer: w, flushEvery: flushEvery},
+		window:  window,
+		pending: make(map[int][]string, window),
+	}
+	ow.cond = sync.NewCond(&ow.m)
+	return ow
+}
+
+// Write writes the row with the sequence number seq, once all the rows before it are written.
+// row may be reused by the caller once Write returns.
+func (ow *OrderedRowWriter) Write(seq int, row []string) error {
+	ow.m.Lock()
+	defer ow.m.Unlock()
+	for ow.sink.err == nil && seq >= ow.next+ow.window {
+		ow.cond.Wait()
+	}
+	if ow.sink.err != nil {
+		return ow.sink.err
+	}
+	if _, ok := ow.pending[seq]; ok || seq < ow.next {
+		return fmt.Errorf("row %d written twice", seq)
+	}
+	if seq > ow.next {
+		ow.pending[seq] = append([]string(nil), row...)
+		return nil
+	}
+	err := ow.sink.write(row)
+	ow.next++
+	for err == nil {
+		r, ok := ow.pending[ow.next]
+		if !ok {
+			break
+		}
+		delete(ow.pending, ow.next)
+		err = ow.sink.write(r)
+		ow.next++
+	}
+	ow.cond.Broadcast()
+	return err
+}
+
+// Abort makes every Write, blocked or to come, and Close return err, for a
+// producer that fails before writing its row. It does nothing once a write
+// failed, or if err is nil.
+func (ow *OrderedRowWriter) Abort(err error) {
+	ow.m.Lock()
+	defer ow.m.Unlock()
+	if ow.sink.err == nil && err != nil {
+		ow.sink.err = err
+		ow.cond.Broadcast()
+	}
+}
+
+// Close flushes the rows written so far. It fails if a row is still waiting
+// for one with a lower sequence number.
+func (ow *OrderedRowWriter) Close() error {
+	ow.m.Lock()
+	defer ow.m.Unlock()
+	if err := ow.sink.flush(); err != nil {
+		return err
+	}
+	if len(ow.pending) > 0 {
+		return fmt.Errorf("row %d was never written", ow.next)
+	}
+	return nil
+}
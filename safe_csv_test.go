@@ -0,0 +1,160 @@
+package gocsv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestRowWriter(t *testing.T) {
+	b := bytes.Buffer{}
+	rw := NewRowWriter(NewSafeCSVWriter(csv.NewWriter(&b)), 10)
+	var wg sync.WaitGroup
+	for p := 0; p < 8; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				if err := rw.Write([]string{strconv.Itoa(p), strconv.Itoa(i)}); err != nil {
+					t.Error(err)
+				}
+			}
+		}(p)
+	}
+	wg.Wait()
+	if err := rw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	lines, err := csv.NewReader(&b).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 800 {
+		t.Fatalf("expected 800 lines, got %d", len(lines))
+	}
+}
+
+func TestOrderedRowWriter(t *testing.T) {
+	b := bytes.Buffer{}
+	ow := NewOrderedRowWriter(NewSafeCSVWriter(csv.NewWriter(&b)), 4, 0)
+	var wg sync.WaitGroup
+	for p := 0; p < 8; p++ {
+		wg.Add(1)
+		go func(p int) {
+			defer wg.Done()
+			for seq := p; seq < 200; seq += 8 {
+				if err := ow.Write(seq, []string{strconv.Itoa(seq)}); err != nil {
+					t.Error(err)
+				}
+			}
+		}(p)
+	}
+	wg.Wait()
+	if err := ow.Close(); err != nil {
+		t.Fatal(err)
+	}
+	lines, err := csv.NewReader(&b).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 200 {
+		t.Fatalf("expected 200 lines, got %d", len(lines))
+	}
+	for i, l := range lines {
+		assertLine(t, []string{strconv.Itoa(i)}, l)
+	}
+
+	ow = NewOrderedRowWriter(NewSafeCSVWriter(csv.NewWriter(&b)), 4, 0)
+	if err := ow.Write(1, []string{"1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ow.Close(); err == nil {
+		t.Fatal("expected an error for the missing row 0")
+	}
+}
+
+func TestOrderedRowWriter_error(t *testing.T) {
+	ow := NewOrderedRowWriter(NewSafeCSVWriter(csv.NewWriter(failingWriter{})), 2, 1)
+	var wg sync.WaitGroup
+	errs := make([]error, 3)
+	for seq := 1; seq < 3; seq++ {
+		wg.Add(1)
+		go func(seq int) {
+			defer wg.Done()
+			errs[seq] = ow.Write(seq, []string{"x"})
+		}(seq)
+	}
+	errs[0] = ow.Write(0, []string{"x"})
+	wg.Wait()
+	if errs[0] == nil || errs[0].Error() != "disk full" {
+		t.Fatalf("expected the write error, got %v", errs[0])
+	}
+	if errs[2] == nil || errs[2].Error() != "disk full" {
+		t.Fatalf("expected the blocked producer to get the write error, got %v", errs[2])
+	}
+	if err := ow.Close(); err == nil || err.Error() != "disk full" {
+		t.Fatalf("expected Close to return the write error, got %v", err)
+	}
+}
+
+func TestOrderedRowWriter_abort(t *testing.T) {
+	ow := NewOrderedRowWriter(NewSafeCSVWriter(csv.NewWriter(&bytes.Buffer{})), 2, 0)
+	done := make(chan error)
+	go func() {
+		done <- ow.Write(2, []string{"2"}) // Blocked until row 0 is written
+	}()
+	fail := errors.New("producer of row 0 failed")
+	ow.Abort(fail)
+	if err := <-done; err != fail {
+		t.Errorf("Write: got %v, wanted %v", err, fail)
+	}
+	if err := ow.Write(1, []string{"1"}); err != fail {
+		t.Errorf("Write after Abort: got %v, wanted %v", err, fail)
+	}
+	if err := ow.Close(); err != fail {
+		t.Errorf("Close: got %v, wanted %v", err, fail)
+	}
+}
+
+func TestSafeCSVWriter_concurrentColumns(t *testing.T) {
+	type quoted struct {
+		A string `csv:"a" quote:"always"`
+	}
+	type plain struct {
+		A string `csv:"a"`
+	}
+	b := bytes.Buffer{}
+	w := NewSafeWriter(NewWriter(&b))
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if err := MarshalCSVWithoutHeaders([]quoted{{"q"}, {"q"}}, w); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if err := MarshalCSVWithoutHeaders([]plain{{"p"}, {"p"}}, w); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	for _, line := range strings.Split(strings.TrimSpace(b.String()), "\n") {
+		if line != `"q"` && line != "p" {
+			t.Fatalf("got line %q, quoted with the columns of the other struct", line)
+		}
+	}
+}
@@ -0,0 +1,610 @@
+package gocsv
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// TableSchema is a Frictionless Data Table Schema, the schema.json descriptor
+// of the fields of a CSV. See https://specs.frictionlessdata.io/table-schema/.
+type TableSchema struct {
+	Fields []SchemaField `json:"fields"`
+	// MissingValues are the values that stand for null, [""] if nil.
+	MissingValues []string `json:"missingValues,omitempty"`
+	PrimaryKey    []string `json:"primaryKey,omitempty"`
+}
+
+// SchemaField is the descriptor of a field of a TableSchema.
+type SchemaField struct {
+	Name        string `json:"name"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Type is string, integer, number, boolean, date, datetime, time, year,
+	// object, array or any, string if empty.
+	Type string `json:"type,omitempty"`
+	// Format is email, uri or uuid for strings, a layout like %d/%m/%Y or
+	// "any" for dates and times, "default" if empty.
+	Format      string             `json:"format,omitempty"`
+	TrueValues  []string           `json:"trueValues,omitempty"`
+	FalseValues []string           `json:"falseValues,omitempty"`
+	Constraints *SchemaConstraints `json:"constraints,omitempty"`
+}
+
+// SchemaConstraints are the constraints on the values of a SchemaField.
+// Minimum and Maximum are numbers for the integer and number fields, and
+// strings in the format of the field for the dates and times.
+type SchemaConstraints struct {
+	Required  bool          `json:"required,omitempty"`
+	Unique    bool          `json:"unique,omitempty"`
+	MinLength *int          `json:"minLength,omitempty"`
+	MaxLength *int          `json:"maxLength,omitempty"`
+	Minimum   interface{}   `json:"minimum,omitempty"`
+	Maximum   interface{}   `json:"maximum,omitempty"`
+	Pattern   string        `json:"pattern,omitempty"`
+	Enum      []interface{} `json:"enum,omitempty"`
+}
+
+// SchemaOf returns the Table Schema of the CSV written by Marshal from values
+// of type T, a struct or a pointer to a struct. The type of each field
+// follows its Go type, and the schema tag adds its constraints and format:
+//
+//	Status string    `csv:"status" schema:"required,enum=active|closed"`
+//	Day    string    `csv:"day" schema:"type=date,format=%d/%m/%Y"`
+//	ID     int       `csv:"id" schema:"required,unique"`
+//
+// The fields of pointers, or tagged omitempty, are never required. The rules
+// of the csv tag are constraints too: minlen, maxlen, pattern and oneof, the
+// latter unless the schema tag has an enum, and min and max for the integer
+// and number fields.
+func SchemaOf[T any]() (*TableSchema, error) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if err := ensureStructOrPtr(t); err != nil {
+		return nil, err
+	}
+	schema := &TableSchema{}
+	for _, f := range getStructInfo(t).Fields {
+		field := SchemaField{Name: f.getFirstKey()}
+		fieldType := t.FieldByIndex(f.IndexChain).Type
+		nullable := f.omitEmpty || fieldType.Kind() == reflect.Ptr
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		field.Type = schemaType(fieldType)
+		if field.Type == "boolean" {
+			field.TrueValues = []string{"true", "True", "TRUE", "yes", "1", "t", "T"}
+			field.FalseValues = []string{"false", "False", "FALSE", "no", "0", "f", "F"}
+		}
+		if err := parseSchemaTag(f, &field, nullable); err != nil {
+			return nil, err
+		}
+		if f.rulesErr != nil {
+			return nil, f.rulesErr
+		}
+		addRuleConstraints(f, &field)
+		schema.Fields = append(schema.Fields, field)
+	}
+	return schema, nil
+}
+
+// schemaType returns the Table Schema type of the values of t.
+func schemaType(t reflect.Type) string {
+	switch {
+	case t == timeType:
+		return "datetime"
+	case t.Implements(marshallerType), reflect.PtrTo(t).Implements(marshallerType),
+		t.Implements(textMarshalerType), reflect.PtrTo(t).Implements(textMarshalerType):
+		return "string"
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.String:
+		return "string"
+	}
+	return "any"
+}
+
+// parseSchemaTag sets the options of the schema tag of f on field. A
+// nullable field is not required.
+func parseSchemaTag(f fieldInfo, field *SchemaField, nullable bool) error {
+	if f.schema == "" {
+		return nil
+	}
+	c := &SchemaConstraints{}
+	for _, option := range strings.Split(f.schema, ",") {
+		name, value, _ := strings.Cut(option, "=")
+		switch name {
+		case "required":
+			c.Required = !nullable
+		case "unique":
+			c.Unique = true
+		case "type":
+			field.Type = value
+		case "format":
+			field.Format = value
+		case "enum":
+			for _, v := range strings.Split(value, "|") {
+				c.Enum = append(c.Enum, v)
+			}
+		default:
+			return fmt.Errorf("field %s: invalid schema tag option %q: unknown option", f.getFirstKey(), option)
+		}
+	}
+	if !reflect.DeepEqual(*c, SchemaConstraints{}) {
+		field.Constraints = c
+	}
+	return nil
+}
+
+// addRuleConstraints adds the rules of the csv tag of f to the constraints
+// of field.
+func addRuleConstraints(f fieldInfo, field *SchemaField) {
+	if len(f.rules) == 0 {
+		return
+	}
+	c := field.Constraints
+	if c == nil {
+		c = &SchemaConstraints{}
+	}
+	numeric := field.Type == "integer" || field.Type == "number"
+	for _, r := range f.rules {
+		switch r.name {
+		case "min":
+			if numeric {
+				c.Minimum = r.num
+				if field.Type == "integer" {
+					c.Minimum = math.Ceil(r.num) // The integers of min=1.5 are from 2
+				}
+			}
+		case "max":
+			if numeric {
+				c.Maximum = r.num
+				if field.Type == "integer" {
+					c.Maximum = math.Floor(r.num)
+				}
+			}
+		case "minlen":
+			n := int(r.num)
+			c.MinLength = &n
+		case "maxlen":
+			n := int(r.num)
+			c.MaxLength = &n
+		case "oneof":
+			if c.Enum == nil {
+				for _, v := range r.values {
+					c.Enum = append(c.Enum, v)
+				}
+			}
+		case "pattern":
+			c.Pattern = r.param
+		}
+	}
+	if !reflect.DeepEqual(*c, SchemaConstraints{}) {
+		field.Constraints = c
+	}
+}
+
+// SchemaViolation is a value, or a header, that does not fit a TableSchema.
+type SchemaViolation struct {
+	// Line is the line of the CSV, 1 for the header.
+	Line int
+	// Column is the position of the value, from 1, or 0 for a missing column.
+	Column int
+	Field  string
+	Value  string
+	// Rule is the violated rule: header, type, format, required, unique,
+	// enum, minLength, maxLength, minimum, maximum or pattern.
+	Rule string
+	Err  error
+}
+
+func (v SchemaViolation) Error() string {
+	if v.Column == 0 {
+		return fmt.Sprintf("line %d, field %s: %v", v.Line, v.Field, v.Err)
+	}
+	return fmt.Sprintf("line %d, column %d (%s): %s: %v", v.Line, v.Column, v.Field, v.Rule, v.Err)
+}
+
+// SchemaError is returned by ValidateAgainstSchema with all the violations of the schema.
+type SchemaError struct {
+	Violations []SchemaViolation
+}
+
+func (e *SchemaError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d schema violations", len(e.Violations))
+	for i, v := range e.Violations {
+		if i == 10 {
+			fmt.Fprintf(&b, "\n...")
+			break
+		}
+		fmt.Fprintf(&b, "\n%v", v)
+	}
+	return b.String()
+}
+
+// schemaColumn is a field of a TableSchema ready to check the values of a column.
+type schemaColumn struct {
+	field   SchemaField
+	index   int
+	layout  string // Go layout of the dates and times, "" for any format
+	pattern *regexp.Regexp
+	trues   map[string]bool
+	falses  map[string]bool
+	min     interface{} // Converted Minimum
+	max     interface{}
+	seen    map[string]bool // Values of the unique fields, by uniqueKey
+}
+
+// ValidateAgainstSchema reads the whole CSV and checks its header and values
+// against the schema. It returns a *SchemaError holding every violation, in
+// the order of the CSV, or nil if none.
+func ValidateAgainstSchema(r io.Reader, schema *TableSchema) error {
+	reader := getCSVReader(r)
+	switch reader := reader.(type) { // The missing values of short rows are violations
+	case *Reader:
+		reader.FieldsPerRecord = -1
+	case *csv.Reader:
+		reader.FieldsPerRecord = -1
+	}
+	headers, err := reader.Read()
+	if err == io.EOF {
+		return ErrEmptyCSV
+	} else if err != nil {
+		return err
+	}
+	trimHeaderBOM(headers)
+
+	var violations []SchemaViolation
+	positions := make(map[string]int, len(headers))
+	for i, h := range headers {
+		if _, ok := positions[h]; !ok {
+			positions[h] = i
+		}
+	}
+	var columns []*schemaColumn
+	known := make(map[string]bool, len(schema.Fields))
+	for _, field := range schema.Fields {
+		known[field.Name] = true
+		index, ok := positions[field.Name]
+		if !ok {
+			violations = append(violations, SchemaViolation{Line: 1, Field: field.Name, Rule: "header",
+				Err: errors.New("missing column")})
+			continue
+		}
+		c, err := newSchemaColumn(field, index)
+		if err != nil {
+			return err
+		}
+		columns = append(columns, c)
+	}
+	for i, h := range headers {
+		if !known[h] {
+			violations = append(violations, SchemaViolation{Line: 1, Column: i + 1, Field: h, Value: h, Rule: "header",
+				Err: errors.New("column not in the schema")})
+		}
+	}
+
+	missing := schema.MissingValues
+	if missing == nil {
+		missing = []string{""}
+	}
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		for _, c := range columns {
+			value := ""
+			if c.index < len(record) {
+				value = record[c.index]
+			}
+			isMissing := false
+			for _, m := range missing {
+				if value == m {
+					isMissing = true
+					break
+				}
+			}
+			if rule, err := c.check(value, isMissing); err != nil {
+				violations = append(violations, SchemaViolation{Line: line, Column: c.index + 1, Field: c.field.Name,
+					Value: value, Rule: rule, Err: err})
+			}
+		}
+	}
+	if len(violations) > 0 {
+		return &SchemaError{Violations: violations}
+	}
+	return nil
+}
+
+func newSchemaColumn(field SchemaField, index int) (*schemaColumn, error) {
+	c := &schemaColumn{field: field, index: index}
+	if c.field.Type == "" {
+		c.field.Type = "string"
+	}
+	switch c.field.Type {
+	case "date", "datetime", "time":
+		layout, err := schemaLayout(c.field.Type, c.field.Format)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %v", field.Name, err)
+		}
+		c.layout = layout
+	case "boolean":
+		trues, falses := field.TrueValues, field.FalseValues
+		if trues == nil {
+			trues = []string{"true", "True", "TRUE", "1"}
+		}
+		if falses == nil {
+			falses = []string{"false", "False", "FALSE", "0"}
+		}
+		c.trues, c.falses = make(map[string]bool), make(map[string]bool)
+		for _, v := range trues {
+			c.trues[v] = true
+		}
+		for _, v := range falses {
+			c.falses[v] = true
+		}
+	}
+	if field.Constraints == nil {
+		return c, nil
+	}
+	if field.Constraints.Pattern != "" {
+		pattern, err := regexp.Compile("^(?:" + field.Constraints.Pattern + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %v", field.Name, err)
+		}
+		c.pattern = pattern
+	}
+	if field.Constraints.Unique {
+		c.seen = make(map[string]bool)
+	}
+	var err error
+	if c.min, err = c.bound(field.Constraints.Minimum); err != nil {
+		return nil, fmt.Errorf("field %s: minimum: %v", field.Name, err)
+	}
+	if c.max, err = c.bound(field.Constraints.Maximum); err != nil {
+		return nil, fmt.Errorf("field %s: maximum: %v", field.Name, err)
+	}
+	return c, nil
+}
+
+// bound converts the Minimum or Maximum of the constraints to the type of
+// the values of the column.
+func (c *schemaColumn) bound(v interface{}) (interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	s := fmt.Sprint(v)
+	if n, ok := v.(json.Number); ok {
+		s = n.String()
+	}
+	if f, ok := v.(float64); ok {
+		s = strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	b, err := c.parse(s)
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// parse returns the value of s, of the type of the column.
+func (c *schemaColumn) parse(s string) (interface{}, error) {
+	switch c.field.Type {
+	case "integer":
+		return strconv.ParseInt(s, 10, 64)
+	case "number":
+		switch s {
+		case "NaN", "INF", "-INF":
+		default:
+			if !isNumeric(s) {
+				return nil, fmt.Errorf("%q is not a number", s)
+			}
+		}
+		return strconv.ParseFloat(strings.Replace(s, "INF", "Inf", 1), 64)
+	case "year":
+		if len(strings.TrimPrefix(s, "-")) != 4 {
+			return nil, fmt.Errorf("%q is not a year", s)
+		}
+		return strconv.ParseInt(s, 10, 64)
+	case "boolean":
+		switch {
+		case c.trues[s]:
+			return true, nil
+		case c.falses[s]:
+			return false, nil
+		}
+		return nil, fmt.Errorf("%q is not a boolean", s)
+	case "date", "datetime", "time":
+		if c.layout == "" {
+			for _, layout := range inferLayouts {
+				if t, err := time.Parse(layout, s); err == nil {
+					return t, nil
+				}
+			}
+			return nil, fmt.Errorf("%q is not a %s", s, c.field.Type)
+		}
+		return time.Parse(c.layout, s)
+	case "object", "array":
+		var v interface{}
+		if err := json.Unmarshal([]byte(s), &v); err != nil {
+			return nil, err
+		}
+		if _, ok := v.(map[string]interface{}); ok != (c.field.Type == "object") {
+			return nil, fmt.Errorf("%q is not an %s", s, c.field.Type)
+		}
+		return v, nil
+	}
+	return s, nil
+}
+
+var (
+	emailPattern = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+	uriPattern   = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9+.-]*:\S+$`)
+	uuidPattern  = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+)
+
+// check returns the rule the value violates, and why, if any.
+func (c *schemaColumn) check(value string, isMissing bool) (string, error) {
+	constraints := c.field.Constraints
+	if constraints == nil {
+		constraints = &SchemaConstraints{}
+	}
+	if isMissing {
+		if constraints.Required {
+			return "required", errors.New("missing value")
+		}
+		return "", nil
+	}
+	v, err := c.parse(value)
+	if err != nil {
+		return "type", fmt.Errorf("not a valid %s: %v", c.field.Type, err)
+	}
+	if c.field.Type == "string" {
+		var pattern *regexp.Regexp
+		switch c.field.Format {
+		case "email":
+			pattern = emailPattern
+		case "uri":
+			pattern = uriPattern
+		case "uuid":
+			pattern = uuidPattern
+		}
+		if pattern != nil && !pattern.MatchString(value) {
+			return "format", fmt.Errorf("not a valid %s", c.field.Format)
+		}
+	}
+	if constraints.Enum != nil {
+		found := false
+		for _, e := range constraints.Enum {
+			if ev, err := c.bound(e); err == nil && reflect.DeepEqual(ev, v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return "enum", fmt.Errorf("not one of %v", constraints.Enum)
+		}
+	}
+	if n := utf8.RuneCountInString(value); constraints.MinLength != nil && n < *constraints.MinLength {
+		return "minLength", fmt.Errorf("shorter than %d", *constraints.MinLength)
+	} else if constraints.MaxLength != nil && n > *constraints.MaxLength {
+		return "maxLength", fmt.Errorf("longer than %d", *constraints.MaxLength)
+	}
+	if c.min != nil && compareSchemaValues(v, c.min) < 0 {
+		return "minimum", fmt.Errorf("less than %v", constraints.Minimum)
+	}
+	if c.max != nil && compareSchemaValues(v, c.max) > 0 {
+		return "maximum", fmt.Errorf("greater than %v", constraints.Maximum)
+	}
+	if c.pattern != nil && !c.pattern.MatchString(value) {
+		return "pattern", fmt.Errorf("does not match %s", constraints.Pattern)
+	}
+	if c.seen != nil {
+		key := uniqueKey(v)
+		if c.seen[key] {
+			return "unique", errors.New("duplicate value")
+		}
+		c.seen[key] = true
+	}
+	return "", nil
+}
+
+// uniqueKey returns the key of the parsed value v for the unique constraint,
+// the same for the values that are equal, as 1 and 01 or the same time in
+// two time zones.
+func uniqueKey(v interface{}) string {
+	switch v := v.(type) {
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	case map[string]interface{}, []interface{}:
+		b, _ := json.Marshal(v) // Sorts the keys of the objects
+		return string(b)
+	}
+	return fmt.Sprint(v)
+}
+
+func compareSchemaValues(a, b interface{}) int {
+	switch a := a.(type) {
+	case int64:
+		return compareOrdered(a, b.(int64))
+	case float64:
+		return compareOrdered(a, b.(float64))
+	case time.Time:
+		return a.Compare(b.(time.Time))
+	case string:
+		return strings.Compare(a, b.(string))
+	}
+	return 0
+}
+
+func compareOrdered[T int64 | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// schemaLayout returns the Go layout of the Table Schema format of dates
+// and times, in which the strftime directives like %Y and %m stand for the
+// parts of the time. It returns "" for the "any" format.
+func schemaLayout(typ, format string) (string, error) {
+	switch format {
+	case "any":
+		return "", nil
+	case "", "default":
+		switch typ {
+		case "date":
+			return "2006-01-02", nil
+		case "time":
+			return "15:04:05", nil
+		}
+		return time.RFC3339Nano, nil
+	}
+	directives := map[byte]string{
+		'Y': "2006", 'y': "06", 'm': "01", 'd': "02", 'e': "_2", 'H': "15", 'I': "03",
+		'M': "04", 'S': "05", 'p': "PM", 'b': "Jan", 'B': "January", 'a': "Mon", 'A': "Monday",
+		'z': "-0700", 'Z': "MST", 'j': "002", '%': "%",
+	}
+	var b strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			b.WriteByte(format[i])
+			continue
+		}
+		if i+1 == len(format) {
+			return "", fmt.Errorf("invalid format %q", format)
+		}
+		i++
+		d, ok := directives[format[i]]
+		if !ok {
+			return "", fmt.Errorf("unsupported directive %%%c in format %q", format[i], format)
+		}
+		b.WriteString(d)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,212 @@
+package gocsv
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type schemaAccount struct {
+	ID      int       `csv:"id" schema:"required,unique"`
+	Email   string    `csv:"email" schema:"format=email"`
+	Status  string    `csv:"status" schema:"required,enum=active|closed"`
+	Balance *float64  `csv:"balance" schema:"required"`
+	Opened  time.Time `csv:"opened"`
+	Day     string    `csv:"day,omitempty" schema:"type=date,format=%d/%m/%Y"`
+	Active  bool      `csv:"active"`
+	Ignored string    `csv:"-"`
+}
+
+func TestSchemaOf(t *testing.T) {
+	schema, err := SchemaOf[*schemaAccount]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := json.Marshal(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"fields":[` +
+		`{"name":"id","type":"integer","constraints":{"required":true,"unique":true}},` +
+		`{"name":"email","type":"string","format":"email"},` +
+		`{"name":"status","type":"string","constraints":{"required":true,"enum":["active","closed"]}},` +
+		`{"name":"balance","type":"number"},` +
+		`{"name":"opened","type":"datetime"},` +
+		`{"name":"day","type":"date","format":"%d/%m/%Y"},` +
+		`{"name":"active","type":"boolean","trueValues":["true","True","TRUE","yes","1","t","T"],"falseValues":["false","False","FALSE","no","0","f","F"]}]}`
+	if string(out) != want {
+		t.Errorf("got\n%s\nwanted\n%s", out, want)
+	}
+
+	type badTag struct {
+		A string `schema:"nope"`
+	}
+	if _, err := SchemaOf[badTag](); err == nil {
+		t.Error("expected an error for an unknown schema tag option")
+	}
+	if _, err := SchemaOf[int](); err == nil {
+		t.Error("expected an error for a type that is not a struct")
+	}
+}
+
+func TestSchemaOf_rules(t *testing.T) {
+	schema, err := SchemaOf[ruledItem]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := json.Marshal(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"fields":[` +
+		`{"name":"name","type":"string","constraints":{"minLength":1,"maxLength":8}},` +
+		`{"name":"age","type":"integer","constraints":{"minimum":0,"maximum":150}},` +
+		`{"name":"price","type":"number","constraints":{"minimum":0.5}},` +
+		`{"name":"status","type":"string","constraints":{"enum":["active","closed"]}},` +
+		`{"name":"sku","type":"string","constraints":{"pattern":"[A-Z]{3}-\\d{1,6}"}}]}`
+	if string(out) != want {
+		t.Errorf("got\n%s\nwanted\n%s", out, want)
+	}
+
+	in := "name,age,price,status,sku\nbob,30,1,active,ABC-1\nbob,151,,open,xABC-1\n"
+	err = ValidateAgainstSchema(strings.NewReader(in), schema)
+	var schemaErr *SchemaError
+	if !errors.As(err, &schemaErr) || len(schemaErr.Violations) != 3 {
+		t.Fatalf("got %v, wanted 3 violations", err)
+	}
+	for i, rule := range []string{"maximum", "enum", "pattern"} {
+		if v := schemaErr.Violations[i]; v.Line != 3 || v.Rule != rule {
+			t.Errorf("violation %d: got %+v, wanted %s on line 3", i, v, rule)
+		}
+	}
+}
+
+func TestSchemaOf_integerBounds(t *testing.T) {
+	type boundedItem struct {
+		ID    int     `csv:"id" schema:"unique"`
+		Count int     `csv:"count,min=1.5,max=9.5"`
+		Ratio float64 `csv:"ratio,min=0.5" schema:"unique"`
+	}
+	schema, err := SchemaOf[boundedItem]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := schema.Fields[1].Constraints; c.Minimum != 2.0 || c.Maximum != 9.0 {
+		t.Errorf("got bounds %v and %v, wanted 2 and 9", c.Minimum, c.Maximum)
+	}
+	in := "id,count,ratio\n1,2,0.5\n01,1,.50\n+1,10,1e0\n2,9,1\n"
+	err = ValidateAgainstSchema(strings.NewReader(in), schema)
+	var schemaErr *SchemaError
+	if !errors.As(err, &schemaErr) {
+		t.Fatalf("got %v, wanted a *SchemaError", err)
+	}
+	var got []string
+	for _, v := range schemaErr.Violations {
+		got = append(got, fmt.Sprintf("%d:%d:%s", v.Line, v.Column, v.Rule))
+	}
+	want := []string{"3:1:unique", "3:2:minimum", "3:3:unique", "4:1:unique", "4:2:maximum", "5:3:unique"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, wanted %v", got, want)
+	}
+}
+
+func TestValidateAgainstSchema(t *testing.T) {
+	schema, err := SchemaOf[schemaAccount]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := "id,email,status,balance,opened,day,active\n" +
+		"1,a@example.com,active,10.5,2024-01-02T10:00:00Z,31/01/2024,true\n" +
+		"2,,closed,,2024-01-03T10:00:00Z,,no\n"
+	if err := ValidateAgainstSchema(strings.NewReader(valid), schema); err != nil {
+		t.Errorf("got %v for a valid CSV", err)
+	}
+
+	invalid := "id,email,status,opened,day,active,extra\n" +
+		"1,not-an-email,open,yesterday,31/02/2024,maybe,x\n" +
+		"1,b@example.com,,2024-01-02T10:00:00Z,01/01/2024,true,y\n" +
+		"x,c@example.com,active\n"
+	err = ValidateAgainstSchema(strings.NewReader(invalid), schema)
+	var schemaErr *SchemaError
+	if !errors.As(err, &schemaErr) {
+		t.Fatalf("got %v, wanted a *SchemaError", err)
+	}
+	type violation struct {
+		Line, Column int
+		Rule         string
+	}
+	var got []violation
+	for _, v := range schemaErr.Violations {
+		got = append(got, violation{v.Line, v.Column, v.Rule})
+	}
+	want := []violation{
+		{1, 0, "header"}, // Missing balance
+		{1, 7, "header"}, // Extra column
+		{2, 2, "format"}, // Email
+		{2, 3, "enum"},   // Status
+		{2, 4, "type"},   // Opened
+		{2, 5, "type"},   // 31/02
+		{2, 6, "type"},   // maybe
+		{3, 1, "unique"}, // Duplicate id
+		{3, 3, "required"},
+		{4, 1, "type"}, // The missing values of the short row are not required
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v\nwanted %v", got, want)
+	}
+}
+
+func TestValidateAgainstSchema_constraints(t *testing.T) {
+	min, max := 2, 4
+	schema := &TableSchema{
+		MissingValues: []string{"", "NA"},
+		Fields: []SchemaField{
+			{Name: "code", Constraints: &SchemaConstraints{MinLength: &min, MaxLength: &max, Pattern: "[A-Z]+"}},
+			{Name: "age", Type: "integer", Constraints: &SchemaConstraints{Minimum: 0.0, Maximum: 150.0}},
+			{Name: "since", Type: "date", Constraints: &SchemaConstraints{Minimum: "2000-01-01"}},
+			{Name: "flag", Type: "boolean"},
+		},
+	}
+	in := "code,age,since,flag\n" +
+		"AB,30,2001-01-01,1\n" +
+		"A,151,1999-12-31,0\n" +
+		"ABCDE,-1,NA,TRUE\n" +
+		"ab,NA,2000-01-01,False\n"
+	err := ValidateAgainstSchema(strings.NewReader(in), schema)
+	var schemaErr *SchemaError
+	if !errors.As(err, &schemaErr) {
+		t.Fatalf("got %v, wanted a *SchemaError", err)
+	}
+	var rules []string
+	for _, v := range schemaErr.Violations {
+		rules = append(rules, v.Rule)
+	}
+	want := []string{"minLength", "maximum", "minimum", "maxLength", "minimum", "pattern"}
+	if !reflect.DeepEqual(rules, want) {
+		t.Errorf("got %v, wanted %v", rules, want)
+	}
+}
+
+func TestSchemaLayout(t *testing.T) {
+	tests := []struct{ typ, format, want string }{
+		{"date", "", "2006-01-02"},
+		{"datetime", "default", time.RFC3339Nano},
+		{"time", "", "15:04:05"},
+		{"date", "%d/%m/%Y", "02/01/2006"},
+		{"datetime", "%Y-%m-%d %H:%M:%S %z", "2006-01-02 15:04:05 -0700"},
+		{"date", "any", ""},
+	}
+	for _, test := range tests {
+		got, err := schemaLayout(test.typ, test.format)
+		if err != nil || got != test.want {
+			t.Errorf("schemaLayout(%q, %q): got %q, %v, wanted %q", test.typ, test.format, got, err, test.want)
+		}
+	}
+	if _, err := schemaLayout("date", "%Q"); err == nil {
+		t.Error("expected an error for an unsupported directive")
+	}
+}
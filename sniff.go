@@ -0,0 +1,345 @@
+package gocsv
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// defaultSniffBytes is the size of the sample read by Sniff when none is given.
+const defaultSniffBytes = 64 << 10
+
+// sniffDelimiters are the delimiters Sniff looks for, by order of preference.
+var sniffDelimiters = []byte{',', ';', '\t', '|', ':'}
+
+// sniffQuotes are the quote characters Sniff looks for, by order of preference.
+var sniffQuotes = []byte{'"', '\''}
+
+// Sniff reads up to sampleBytes from r, 64KiB if sampleBytes is not positive,
+// and guesses the Dialect of the CSV: its delimiter, quote and escape
+// characters, record terminator, whether the first record is a header, and
+// its encoding from a byte order mark or the bytes that are not UTF-8.
+// The sample is consumed from r, see SniffDialect to sniff and then decode
+// the whole input. An empty sample gives ErrEmptyCSV.
+func Sniff(r io.Reader, sampleBytes int) (Dialect, error) {
+	if sampleBytes <= 0 {
+		sampleBytes = defaultSniffBytes
+	}
+	sample := make([]byte, sampleBytes)
+	n, err := io.ReadFull(r, sample)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return Dialect{}, err
+	}
+	return sniff(sample[:n], n == sampleBytes)
+}
+
+// sniff guesses the Dialect of sample. When truncated, the sample was cut
+// from a longer input and its last line is ignored, as it may be incomplete.
+func sniff(sample []byte, truncated bool) (Dialect, error) {
+	text, enc := decodeSample(sample, truncated)
+	if truncated {
+		if i := strings.LastIndexAny(text, "\r\n"); i >= 0 {
+			text = text[:i+1]
+		}
+	}
+	if strings.TrimSpace(text) == "" {
+		return Dialect{}, ErrEmptyCSV
+	}
+
+	d := DefaultDialect()
+	d.Encoding = enc
+	d.RecordTerminator = sniffTerminator(text)
+	quote := sniffQuote(text)
+	d.Quote = rune(quote)
+	d.Delimiter = string(sniffDelimiter(text, quote))
+	if sniffEscape(text, quote) {
+		d.Escape = '\\'
+		d.DoubleQuote = hasDoubledQuote(text, quote)
+	}
+
+	reader := NewDialectReader(strings.NewReader(text), d)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	var rows [][]string
+	for {
+		row, err := reader.Read()
+		if err != nil {
+			break
+		}
+		rows = append(rows, row)
+	}
+	d.Header = sniffHeader(rows)
+	return d, nil
+}
+
+// decodeSample returns the text of sample and its likely encoding. Text that
+// is not UTF-8 nor UTF-16 is decoded byte per byte, which is enough to find
+// the ASCII separators.
+func decodeSample(sample []byte, truncated bool) (string, Encoding) {
+	switch {
+	case bytes.HasPrefix(sample, []byte{0xEF, 0xBB, 0xBF}):
+		return string(sample[3:]), UTF8BOM
+	case bytes.HasPrefix(sample, []byte{0xFF, 0xFE}):
+		return decodeUTF16(sample[2:], false), UTF16LE
+	case bytes.HasPrefix(sample, []byte{0xFE, 0xFF}):
+		return decodeUTF16(sample[2:], true), UTF16BE
+	}
+	// ASCII text in UTF-16 has a zero byte in every other position.
+	var zeros [2]int
+	for i, b := range sample {
+		if b == 0 {
+			zeros[i%2]++
+		}
+	}
+	if zeros[1] > len(sample)/4 && zeros[0] == 0 {
+		return decodeUTF16(sample, false), UTF16LE
+	}
+	if zeros[0] > len(sample)/4 && zeros[1] == 0 {
+		return decodeUTF16(sample, true), UTF16BE
+	}
+
+	if truncated { // Do not judge a rune cut at the end of the sample
+		if i := bytes.LastIndexByte(sample, '\n'); i >= 0 {
+			sample = sample[:i+1]
+		}
+	}
+	if utf8.Valid(sample) {
+		return string(sample), UTF8
+	}
+	enc := Windows1252
+	runes := make([]rune, len(sample))
+	for i, b := range sample {
+		switch b {
+		case 0x81, 0x8D, 0x8F, 0x90, 0x9D: // Not defined in Windows-1252
+			enc = ISO88591
+		}
+		runes[i] = rune(b)
+	}
+	return string(runes), enc
+}
+
+func decodeUTF16(b []byte, bigEndian bool) string {
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		if bigEndian {
+			units[i] = uint16(b[2*i])<<8 | uint16(b[2*i+1])
+		} else {
+			units[i] = uint16(b[2*i+1])<<8 | uint16(b[2*i])
+		}
+	}
+	return string(utf16.Decode(units))
+}
+
+// sniffTerminator returns the line ending of the first line of text.
+func sniffTerminator(text string) string {
+	i := strings.IndexAny(text, "\r\n")
+	switch {
+	case i < 0 || text[i] == '\n':
+		return "\n"
+	case strings.HasPrefix(text[i:], "\r\n"):
+		return "\r\n"
+	}
+	return "\r"
+}
+
+// isSniffBoundary reports whether c may surround a quoted field.
+func isSniffBoundary(c byte) bool {
+	return c == '\r' || c == '\n' || bytes.IndexByte(sniffDelimiters, c) >= 0
+}
+
+// sniffQuote returns the character that most often opens and closes fields.
+func sniffQuote(text string) byte {
+	best, bestScore := sniffQuotes[0], 0
+	for _, q := range sniffQuotes {
+		opens, closes := 0, 0
+		for i := 0; i < len(text); i++ {
+			if text[i] != q {
+				continue
+			}
+			if i == 0 || isSniffBoundary(text[i-1]) {
+				opens++
+			}
+			if i == len(text)-1 || isSniffBoundary(text[i+1]) {
+				closes++
+			}
+		}
+		if opens > 0 && closes > 0 && opens+closes > bestScore {
+			best, bestScore = q, opens+closes
+		}
+	}
+	return best
+}
+
+// sniffDelimiter returns the delimiter found the same number of times, out
+// of quotes, in most lines. Text with no delimiter is a single column, for
+// which the default delimiter is returned.
+func sniffDelimiter(text string, quote byte) byte {
+	lines := splitSniffLines(text, quote)
+	best, bestScore := sniffDelimiters[0], 0.0
+	for _, delim := range sniffDelimiters {
+		counts := make(map[int]int)
+		for _, line := range lines {
+			if n := countOutsideQuotes(line, delim, quote); n > 0 {
+				counts[n]++
+			}
+		}
+		mode := 0
+		for _, frequency := range counts {
+			if frequency > mode {
+				mode = frequency
+			}
+		}
+		if score := float64(mode) / float64(len(lines)); score > bestScore {
+			best, bestScore = delim, score
+		}
+	}
+	return best
+}
+
+// splitSniffLines splits text into its non-empty lines, keeping the line
+// breaks of quoted fields.
+func splitSniffLines(text string, quote byte) []string {
+	var lines []string
+	inQuotes, start := false, 0
+	for i := 0; i <= len(text); i++ {
+		if i < len(text) && text[i] == quote {
+			inQuotes = !inQuotes
+		}
+		if i == len(text) || (!inQuotes && (text[i] == '\n' || text[i] == '\r')) {
+			if line := text[start:i]; line != "" {
+				lines = append(lines, line)
+			}
+			start = i + 1
+		}
+	}
+	return lines
+}
+
+func countOutsideQuotes(line string, c, quote byte) int {
+	n, inQuotes := 0, false
+	for i := 0; i < len(line); i++ {
+		switch line[i] {
+		case quote:
+			inQuotes = !inQuotes
+		case c:
+			if !inQuotes {
+				n++
+			}
+		}
+	}
+	return n
+}
+
+// sniffEscape reports whether the quote is escaped with a backslash in the
+// middle of fields.
+func sniffEscape(text string, quote byte) bool {
+	for i := 0; i+2 < len(text); i++ {
+		if text[i] == '\\' && text[i+1] == quote && !isSniffBoundary(text[i+2]) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasDoubledQuote reports whether text holds two quotes in a row that are
+// not an escaped quote followed by the closing quote.
+func hasDoubledQuote(text string, quote byte) bool {
+	for i := 0; i+1 < len(text); i++ {
+		if text[i] == quote && text[i+1] == quote && (i == 0 || text[i-1] != '\\') {
+			return true
+		}
+	}
+	return false
+}
+
+// sniffKind is the kind of values of a column, for the header detection.
+type sniffKind int
+
+const (
+	sniffUnknown sniffKind = iota // No value seen yet
+	sniffMixed
+	sniffString
+	sniffInt
+	sniffFloat
+	sniffBool
+)
+
+func valueKind(s string) sniffKind {
+	if _, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return sniffInt
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return sniffFloat
+	}
+	if l := strings.ToLower(s); l == "true" || l == "false" {
+		return sniffBool
+	}
+	return sniffString
+}
+
+func mergeKinds(a, b sniffKind) sniffKind {
+	switch {
+	case a == sniffUnknown || a == b:
+		return b
+	case (a == sniffInt && b == sniffFloat) || (a == sniffFloat && b == sniffInt):
+		return sniffFloat
+	}
+	return sniffMixed
+}
+
+// sniffHeader reports whether the first row is a header. Each column votes
+// for a header when its first value does not fit the type, or the length, of
+// all its other values, and against it when it does. Without votes, the first
+// row is a header if its values look like distinct names.
+func sniffHeader(rows [][]string) bool {
+	if len(rows) == 0 {
+		return false
+	}
+	header := rows[0]
+	votes := 0
+	for j, h := range header {
+		kind, length := sniffUnknown, -1
+		for _, row := range rows[1:] {
+			if len(row) != len(header) || row[j] == "" {
+				continue
+			}
+			kind = mergeKinds(kind, valueKind(row[j]))
+			if length == -1 {
+				length = len(row[j])
+			} else if length != len(row[j]) {
+				length = -2
+			}
+		}
+		switch kind {
+		case sniffUnknown, sniffMixed:
+		case sniffString:
+			if length >= 0 {
+				if len(h) != length {
+					votes++
+				} else {
+					votes--
+				}
+			}
+		default:
+			if h == "" || mergeKinds(kind, valueKind(h)) != kind {
+				votes++
+			} else {
+				votes--
+			}
+		}
+	}
+	if votes != 0 {
+		return votes > 0
+	}
+	names := make(map[string]bool, len(header))
+	for _, h := range header {
+		if h == "" || names[h] || valueKind(h) != sniffString {
+			return false
+		}
+		names[h] = true
+	}
+	return true
+}
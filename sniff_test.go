@@ -0,0 +1,109 @@
+package gocsv
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSniff(t *testing.T) {
+	tests := []struct {
+		name     string
+		in       string
+		expected Dialect
+	}{{
+		name:     "comma",
+		in:       "foo,BAR,Baz\nf,1,baz\ne,3,b\n",
+		expected: Dialect{Delimiter: ",", Quote: '"', DoubleQuote: true, RecordTerminator: "\n", Header: true},
+	}, {
+		name:     "semicolon CRLF",
+		in:       "name;amount\r\n\"a;b\";1,5\r\nc;2,25\r\n",
+		expected: Dialect{Delimiter: ";", Quote: '"', DoubleQuote: true, RecordTerminator: "\r\n", Header: true},
+	}, {
+		name:     "tab single quotes",
+		in:       "'x'\t'y'\n'a\tb'\t1\n'c'\t2\n",
+		expected: Dialect{Delimiter: "\t", Quote: '\'', DoubleQuote: true, RecordTerminator: "\n", Header: true},
+	}, {
+		name:     "pipe no header",
+		in:       "1|2.5|true\n2|3.5|false\n3|4|true\n",
+		expected: Dialect{Delimiter: "|", Quote: '"', DoubleQuote: true, RecordTerminator: "\n"},
+	}, {
+		name:     "backslash escape",
+		in:       "id,text\n1,\"say \\\"hi\\\"\"\n2,\"x\"\n",
+		expected: Dialect{Delimiter: ",", Quote: '"', Escape: '\\', RecordTerminator: "\n", Header: true},
+	}, {
+		name:     "UTF-8 BOM",
+		in:       "\xEF\xBB\xBFa,b\n1,2\n",
+		expected: Dialect{Delimiter: ",", Quote: '"', DoubleQuote: true, RecordTerminator: "\n", Header: true, Encoding: UTF8BOM},
+	}, {
+		name:     "UTF-16LE",
+		in:       "\xFF\xFEa\x00;\x00b\x00\n\x001\x00;\x002\x00\n\x00",
+		expected: Dialect{Delimiter: ";", Quote: '"', DoubleQuote: true, RecordTerminator: "\n", Header: true, Encoding: UTF16LE},
+	}, {
+		name:     "Windows-1252",
+		in:       "caf\xe9,prix\n\x80,3\n",
+		expected: Dialect{Delimiter: ",", Quote: '"', DoubleQuote: true, RecordTerminator: "\n", Header: true, Encoding: Windows1252},
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			d, err := Sniff(strings.NewReader(test.in), 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d != test.expected {
+				t.Fatalf("expected %+v, got %+v", test.expected, d)
+			}
+		})
+	}
+}
+
+func TestSniff_truncatedSample(t *testing.T) {
+	in := "a;b\n1;2\n3;4,5,6,7,8,9\n"
+	d, err := Sniff(strings.NewReader(in), len("a;b\n1;2\n3;4,5"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Delimiter != ";" {
+		t.Fatalf("expected the incomplete last line to be ignored, got delimiter %q", d.Delimiter)
+	}
+}
+
+func TestSniff_empty(t *testing.T) {
+	if _, err := Sniff(strings.NewReader(""), 0); err != ErrEmptyCSV {
+		t.Fatalf("expected ErrEmptyCSV, got %v", err)
+	}
+}
+
+type sniffSample struct {
+	Foo string `csv:"foo"`
+	Bar int    `csv:"BAR"`
+	Baz string `csv:"Baz"`
+}
+
+func TestUnmarshalDecoder_sniffDialect(t *testing.T) {
+	in := "foo|BAR|Baz\r\n'f|1'|1|baz\r\ne|3|b\r\n"
+	var samples []sniffSample
+	if err := UnmarshalDecoder(NewDecoder(strings.NewReader(in), SniffDialect(0)), &samples); err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != 2 || samples[0].Foo != "f|1" || samples[1].Baz != "b" {
+		t.Fatalf("unexpected samples %+v", samples)
+	}
+
+	// The sample ends in the middle of the last record, which is read whole.
+	var fromChan []sniffSample
+	c := make(chan sniffSample)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for s := range c {
+			fromChan = append(fromChan, s)
+		}
+	}()
+	if err := UnmarshalDecoderToChan(NewDecoder(strings.NewReader(in), SniffDialect(30)), c); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+	if len(fromChan) != 2 || fromChan[0].Foo != "f|1" || fromChan[1].Baz != "b" {
+		t.Fatalf("unexpected samples %+v", fromChan)
+	}
+}
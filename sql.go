@@ -0,0 +1,266 @@
+package gocsv
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// defaultTxSize is the number of rows LoadCSV executes per transaction when
+// no TxSize is given.
+const defaultTxSize = 1000
+
+// NullValue makes MarshalRows write s for the NULL values, instead of an
+// empty field. The other encoders reject it.
+func NullValue(s string) EncoderOption {
+	return func(o *encoderOptions) {
+		o.null = s
+	}
+}
+
+// TimeLayout makes MarshalRows format the time values with layout, instead
+// of time.RFC3339Nano. The other encoders reject it.
+func TimeLayout(layout string) EncoderOption {
+	return func(o *encoderOptions) {
+		o.timeLayout = layout
+	}
+}
+
+// MarshalRows writes the rows in w, after a header of their columns unless
+// WithoutHeaders is given, and closes them. Text and []byte values are
+// written as they are, time values with the TimeLayout and NULL values as
+// the NullValue. Of the other options, it rejects the ones it cannot apply.
+func MarshalRows(rows *sql.Rows, w *SafeCSVWriter, opts ...EncoderOption) error {
+	defer rows.Close()
+	o := newEncoderOptions(opts)
+	if err := o.check(true, true); err != nil {
+		return err
+	}
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	if !o.omitHeaders {
+		if err := w.Write(columns); err != nil {
+			return err
+		}
+	}
+	values := make([]interface{}, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	record := make([]string, len(columns))
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+		for i, v := range values {
+			if record[i], err = o.formatSQLValue(v); err != nil {
+				return fmt.Errorf("column %s: %v", columns[i], err)
+			}
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// formatSQLValue returns the CSV field of a value scanned from sql.Rows.
+func (o *encoderOptions) formatSQLValue(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case nil:
+		return o.null, nil
+	case []byte:
+		return string(v), nil
+	case time.Time:
+		layout := o.timeLayout
+		if layout == "" {
+			layout = time.RFC3339Nano
+		}
+		return v.Format(layout), nil
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32), nil
+	}
+	return toString(v)
+}
+
+// LoadOption configures LoadCSV.
+type LoadOption func(*loadOptions)
+
+type loadOptions struct {
+	txSize int
+	null   *string
+}
+
+// TxSize makes LoadCSV execute n rows per transaction, 1000 by default.
+func TxSize(n int) LoadOption {
+	return func(o *loadOptions) {
+		o.txSize = n
+	}
+}
+
+// LoadNull makes LoadCSV pass NULL for the fields equal to s, instead of
+// the string.
+func LoadNull(s string) LoadOption {
+	return func(o *loadOptions) {
+		o.null = &s
+	}
+}
+
+// LoadError is returned by LoadCSV when a row fails to load. The rows of the
+// transactions committed before, Loaded rows in all, stay in the database.
+type LoadError struct {
+	// Line is the line where the row starts, when the CSVReader reports the
+	// positions of its fields as csv.Reader does, else the record number.
+	Line   int
+	Loaded int64
+	Err    error
+}
+
+func (e *LoadError) Error() string {
+	return fmt.Sprintf("loading line %d: %v", e.Line, e.Err)
+}
+
+func (e *LoadError) Unwrap() error {
+	return e.Err
+}
+
+// LoadCSV executes the statement for each row of the CSV read from r, in
+// transactions of TxSize rows. The arguments of the statement are the fields
+// of the columns named by mapping, in order, or all the fields of the rows
+// if mapping is nil. Each transaction is committed before the next begins;
+// if a row fails, its transaction is rolled back and a *LoadError returned.
+func LoadCSV(db *sql.DB, stmt string, r io.Reader, mapping []string, opts ...LoadOption) error {
+	o := loadOptions{txSize: defaultTxSize}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.txSize <= 0 {
+		return fmt.Errorf("invalid transaction size %d", o.txSize)
+	}
+	reader := getCSVReader(r)
+	headers, err := reader.Read()
+	if err == io.EOF {
+		return ErrEmptyCSV
+	} else if err != nil {
+		return err
+	}
+	trimHeaderBOM(headers)
+	pos, _ := reader.(fieldPositioner) // Else the lines are record numbers
+	indexes, err := mappingIndexes(headers, mapping)
+	if err != nil {
+		return err
+	}
+
+	var (
+		tx       *sql.Tx
+		st       *sql.Stmt
+		pending  int64 // Rows executed in tx
+		loaded   int64 // Rows committed
+		line     = 1
+		loadFail = func(err error) error {
+			if tx != nil {
+				tx.Rollback()
+			}
+			return &LoadError{Line: line, Loaded: loaded, Err: err}
+		}
+	)
+	commit := func() error {
+		st.Close()
+		err := tx.Commit()
+		tx = nil
+		if err != nil {
+			return &LoadError{Line: line, Loaded: loaded, Err: err}
+		}
+		loaded += pending
+		pending = 0
+		return nil
+	}
+	args := make([]interface{}, len(indexes))
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		var parseErr *csv.ParseError
+		switch {
+		case errors.As(err, &parseErr):
+			line = parseErr.StartLine
+		case err == nil && pos != nil:
+			line, _ = pos.FieldPos(0)
+		}
+		if err != nil {
+			return loadFail(err)
+		}
+		if tx == nil {
+			if tx, err = db.Begin(); err != nil {
+				return loadFail(err)
+			}
+			if st, err = tx.Prepare(stmt); err != nil {
+				return loadFail(err)
+			}
+		}
+		for i, index := range indexes {
+			switch {
+			case index >= len(record):
+				return loadFail(fmt.Errorf("missing column %s", headers[index]))
+			case o.null != nil && record[index] == *o.null:
+				args[i] = nil
+			default:
+				args[i] = record[index]
+			}
+		}
+		if _, err := st.Exec(args...); err != nil {
+			return loadFail(err)
+		}
+		pending++
+		if pending == int64(o.txSize) {
+			if err := commit(); err != nil {
+				return err
+			}
+		}
+	}
+	if tx != nil {
+		return commit()
+	}
+	return nil
+}
+
+// mappingIndexes returns the positions of the mapped columns in headers.
+func mappingIndexes(headers, mapping []string) ([]int, error) {
+	if mapping == nil {
+		indexes := make([]int, len(headers))
+		for i := range indexes {
+			indexes[i] = i
+		}
+		return indexes, nil
+	}
+	positions := make(map[string]int, len(headers))
+	for i := len(headers) - 1; i >= 0; i-- { // The first of duplicate headers
+		positions[headers[i]] = i
+	}
+	indexes := make([]int, len(mapping))
+	var missing []string
+	for i, name := range mapping {
+		index, ok := positions[name]
+		if !ok {
+			missing = append(missing, name)
+		}
+		indexes[i] = index
+	}
+	if len(missing) > 0 {
+		return nil, MissingColumnsError{MissingColumnNames: missing}
+	}
+	return indexes, nil
+}
@@ -0,0 +1,267 @@
+package gocsv
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/csv"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeDB is the state of the fake driver: the rows returned by queries, and
+// the arguments of the statements executed in committed transactions.
+type fakeDB struct {
+	mu        sync.Mutex
+	columns   []string
+	rows      [][]driver.Value
+	committed [][]driver.Value
+	commits   int
+	rollbacks int
+}
+
+type fakeDriver struct {
+	db *fakeDB
+}
+
+var fakeDrivers = struct {
+	sync.Mutex
+	n int
+}{}
+
+// openFakeDB registers a new fake driver, and opens it.
+func openFakeDB(t *testing.T, db *fakeDB) *sql.DB {
+	fakeDrivers.Lock()
+	fakeDrivers.n++
+	name := "gocsv-fake-" + string(rune('a'+fakeDrivers.n))
+	fakeDrivers.Unlock()
+	sql.Register(name, fakeDriver{db})
+	sqlDB, err := sql.Open(name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sqlDB.Close() })
+	return sqlDB
+}
+
+func (d fakeDriver) Open(string) (driver.Conn, error) {
+	return &fakeConn{db: d.db}, nil
+}
+
+type fakeConn struct {
+	db      *fakeDB
+	pending [][]driver.Value
+	inTx    bool
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return fakeStmt{c}, nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.inTx = true
+	return c, nil
+}
+
+func (c *fakeConn) Commit() error {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.committed = append(c.db.committed, c.pending...)
+	c.db.commits++
+	c.pending, c.inTx = nil, false
+	return nil
+}
+
+func (c *fakeConn) Rollback() error {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	c.db.rollbacks++
+	c.pending, c.inTx = nil, false
+	return nil
+}
+
+type fakeStmt struct {
+	c *fakeConn
+}
+
+func (s fakeStmt) Close() error {
+	return nil
+}
+
+func (s fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	for _, arg := range args {
+		if arg == "fail" {
+			return nil, errors.New("constraint violated")
+		}
+	}
+	s.c.pending = append(s.c.pending, args)
+	return driver.RowsAffected(1), nil
+}
+
+func (s fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return &fakeRows{columns: s.c.db.columns, rows: s.c.db.rows}, nil
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	return r.columns
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func TestMarshalRows(t *testing.T) {
+	at := time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC)
+	db := openFakeDB(t, &fakeDB{
+		columns: []string{"id", "name", "data", "score", "active", "at"},
+		rows: [][]driver.Value{
+			{int64(1), "Alice", []byte("a,b"), 9.5, true, at},
+			{int64(2), nil, nil, nil, false, nil},
+		},
+	})
+
+	tests := []struct {
+		opts []EncoderOption
+		want string
+	}{
+		{nil, "id,name,data,score,active,at\n" +
+			"1,Alice,\"a,b\",9.5,true,2024-03-01T12:30:00Z\n" +
+			"2,,,,false,\n"},
+		{[]EncoderOption{WithoutHeaders(), NullValue(`\N`), TimeLayout("2006-01-02")}, "" +
+			"1,Alice,\"a,b\",9.5,true,2024-03-01\n" +
+			"2,\\N,\\N,\\N,false,\\N\n"},
+	}
+	for _, test := range tests {
+		rows, err := db.Query("SELECT *")
+		if err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := MarshalRows(rows, DefaultCSVWriter(&buf), test.opts...); err != nil {
+			t.Fatal(err)
+		}
+		if got := buf.String(); got != test.want {
+			t.Errorf("got\n%s\nwanted\n%s", got, test.want)
+		}
+	}
+}
+
+func TestLoadCSV(t *testing.T) {
+	state := &fakeDB{}
+	db := openFakeDB(t, state)
+	in := "\uFEFFid,name,age\n1,Alice,34\n2,Bob,\n3,Carol,28\n4,Dan,51\n5,Eve,19\n"
+	err := LoadCSV(db, "INSERT INTO clients (name, age) VALUES (?, ?)", strings.NewReader(in),
+		[]string{"name", "age"}, TxSize(2), LoadNull(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]driver.Value{{"Alice", "34"}, {"Bob", nil}, {"Carol", "28"}, {"Dan", "51"}, {"Eve", "19"}}
+	if !reflect.DeepEqual(state.committed, want) {
+		t.Errorf("got %v, wanted %v", state.committed, want)
+	}
+	if state.commits != 3 {
+		t.Errorf("got %d commits, wanted 3", state.commits)
+	}
+
+	// All the columns, without mapping
+	state.committed = nil
+	if err := LoadCSV(db, "INSERT", strings.NewReader("a,b\nx,\n"), nil); err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]driver.Value{{"x", ""}}; !reflect.DeepEqual(state.committed, want) {
+		t.Errorf("got %v, wanted %v", state.committed, want)
+	}
+}
+
+func TestLoadCSV_errors(t *testing.T) {
+	state := &fakeDB{}
+	db := openFakeDB(t, state)
+	in := "name\nAlice\nBob\nCarol\nfail\nEve\n"
+	err := LoadCSV(db, "INSERT", strings.NewReader(in), nil, TxSize(2))
+	var loadErr *LoadError
+	if !errors.As(err, &loadErr) {
+		t.Fatalf("got %v, wanted a *LoadError", err)
+	}
+	if loadErr.Line != 5 || loadErr.Loaded != 2 {
+		t.Errorf("got line %d and %d rows loaded, wanted line 5 and 2 rows", loadErr.Line, loadErr.Loaded)
+	}
+	if want := [][]driver.Value{{"Alice"}, {"Bob"}}; !reflect.DeepEqual(state.committed, want) {
+		t.Errorf("got %v, wanted %v", state.committed, want)
+	}
+	if state.rollbacks != 1 {
+		t.Errorf("got %d rollbacks, wanted 1", state.rollbacks)
+	}
+
+	// The lines follow the fields spanning several lines, and the errors of the reader
+	in = "name\n\"Alice\nA.\"\n\"Bob\nB.\"\nfail\n\"Eve\n"
+	for _, reader := range []func(io.Reader) CSVReader{DefaultCSVReader, func(in io.Reader) CSVReader { return NewReader(in) }} {
+		SetCSVReader(reader)
+		if err := LoadCSV(db, "INSERT", strings.NewReader(in), nil); !errors.As(err, &loadErr) || loadErr.Line != 6 {
+			t.Errorf("got %v, wanted a *LoadError on line 6", err)
+		}
+		if err := LoadCSV(db, "INSERT", strings.NewReader(strings.Replace(in, "fail", "Dan", 1)), nil); !errors.As(err, &loadErr) || loadErr.Line != 7 {
+			t.Errorf("got %v, wanted a *LoadError on line 7", err)
+		}
+	}
+	SetCSVReader(DefaultCSVReader)
+
+	err = LoadCSV(db, "INSERT", strings.NewReader("a,b\n1,2\n"), []string{"a", "c"})
+	if _, ok := err.(MissingColumnsError); !ok {
+		t.Errorf("got %v, wanted a MissingColumnsError", err)
+	}
+	if err := LoadCSV(db, "INSERT", strings.NewReader(""), nil); err != ErrEmptyCSV {
+		t.Errorf("got %v, wanted ErrEmptyCSV", err)
+	}
+	if err := LoadCSV(db, "INSERT", strings.NewReader("a\n1\n"), nil, TxSize(0)); err == nil {
+		t.Error("expected an error for a transaction size of 0")
+	}
+}
+
+func TestEncoderOptions_unsupported(t *testing.T) {
+	if err := MarshalWithOptions([]Sample{}, &bytes.Buffer{}, NullValue("NULL")); err != errSQLOptions {
+		t.Errorf("MarshalWithOptions: expected errSQLOptions, got %v", err)
+	}
+	c := make(chan Sample)
+	close(c)
+	w := NewSafeCSVWriter(csv.NewWriter(&bytes.Buffer{}))
+	if err := MarshalFromChan(context.Background(), c, w, TimeLayout(time.Kitchen)); err != errSQLOptions {
+		t.Errorf("MarshalFromChan: expected errSQLOptions, got %v", err)
+	}
+	if err := MarshalFromChan(context.Background(), c, w, Compress(Gzip)); err != errSafeOptions {
+		t.Errorf("MarshalFromChan: expected errSafeOptions, got %v", err)
+	}
+	if err := (&encoderOptions{encoding: UTF16LE}).check(true, true); err != errSafeOptions {
+		t.Errorf("MarshalRows: expected errSafeOptions, got %v", err)
+	}
+	if err := (&encoderOptions{validateRules: true}).check(true, true); err != errRowsRules {
+		t.Errorf("MarshalRows: expected errRowsRules, got %v", err)
+	}
+}
@@ -0,0 +1,416 @@
+// Package sqldriver is a database/sql driver querying directories of CSV files.
+//
+// The data source name is the path of a directory, of which each .csv file is
+// a table named after the file, its first record being the header:
+//
+//	db, err := sql.Open("gocsv", "./data")
+//	rows, err := db.Query("SELECT name, age FROM clients WHERE age >= ? ORDER BY name LIMIT 10", 18)
+//
+// The driver is read-only and supports a subset of SELECT: a list of columns
+// or *, a WHERE clause of comparisons combined with AND, OR and NOT, ORDER BY
+// and LIMIT. The type of each column, INTEGER, REAL, BOOLEAN or TEXT, is
+// inferred from all its values; empty values are NULL but in TEXT columns.
+// The rows are streamed from the file, but for ORDER BY.
+package sqldriver
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/gocarina/gocsv"
+)
+
+// ErrReadOnly is returned by the statements and transactions other than SELECT.
+var ErrReadOnly = errors.New("sqldriver: the CSV files are read-only")
+
+func init() {
+	sql.Register("gocsv", &Driver{})
+}
+
+// Driver is the driver registered as "gocsv".
+type Driver struct{}
+
+// Open returns a connection to the directory named by dsn.
+func (d *Driver) Open(dsn string) (driver.Conn, error) {
+	stat, err := os.Stat(dsn)
+	if err != nil {
+		return nil, err
+	}
+	if !stat.IsDir() {
+		return nil, fmt.Errorf("sqldriver: %s is not a directory", dsn)
+	}
+	return &conn{dir: dsn}, nil
+}
+
+type conn struct {
+	dir string
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	q, err := parse(query)
+	if err != nil {
+		return nil, err
+	}
+	return &stmt{conn: c, query: q}, nil
+}
+
+func (c *conn) Close() error {
+	return nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return nil, ErrReadOnly
+}
+
+// table returns the path of the file of the named table.
+func (c *conn) table(name string) (string, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) || name == "." || name == ".." {
+		return "", fmt.Errorf("sqldriver: invalid table name %q", name)
+	}
+	if !strings.HasSuffix(strings.ToLower(name), ".csv") {
+		name += ".csv"
+	}
+	path := filepath.Join(c.dir, name)
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return "", fmt.Errorf("sqldriver: no such table %q", strings.TrimSuffix(name, ".csv"))
+	}
+	return path, nil
+}
+
+type stmt struct {
+	conn  *conn
+	query *query
+}
+
+func (s *stmt) Close() error {
+	return nil
+}
+
+func (s *stmt) NumInput() int {
+	return s.query.inputs
+}
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, ErrReadOnly
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.query.run(context.Background(), s.conn, args)
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	values := make([]driver.Value, len(args))
+	for i, arg := range args {
+		if arg.Name != "" {
+			return nil, fmt.Errorf("sqldriver: named arguments are not supported")
+		}
+		values[i] = arg.Value
+	}
+	return s.query.run(ctx, s.conn, values)
+}
+
+// run starts the query of the table with the values of its placeholders.
+func (q *query) run(ctx context.Context, c *conn, args []driver.Value) (driver.Rows, error) {
+	path, err := c.table(q.table)
+	if err != nil {
+		return nil, err
+	}
+	t, err := loadTable(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &rows{ctx: ctx, table: t, query: q, args: args, limit: -1}
+	if q.columns == nil {
+		for i := range t.columns {
+			r.indexes = append(r.indexes, i)
+		}
+	}
+	for _, name := range q.columns {
+		i := t.column(name)
+		if i < 0 {
+			return nil, fmt.Errorf("sqldriver: no such column %q in %s", name, q.table)
+		}
+		r.indexes = append(r.indexes, i)
+	}
+	if err := q.checkColumns(t, q.where); err != nil {
+		return nil, err
+	}
+	if q.limit != nil {
+		limit, _ := r.operand(*q.limit, nil).(int64)
+		if limit < 0 {
+			return nil, fmt.Errorf("sqldriver: LIMIT must be a positive integer")
+		}
+		r.limit = int(limit)
+	}
+
+	f, reader, err := openTable(path)
+	if err != nil {
+		return nil, err
+	}
+	r.file, r.reader = f, reader
+	if _, err := reader.Read(); err != nil { // The header
+		f.Close()
+		return nil, err
+	}
+	if len(q.orderBy) > 0 {
+		if err := r.sort(); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// checkColumns checks the columns of the condition exist in the table.
+func (q *query) checkColumns(t *table, e expr) error {
+	switch e := e.(type) {
+	case logicalExpr:
+		if err := q.checkColumns(t, e.left); err != nil {
+			return err
+		}
+		return q.checkColumns(t, e.right)
+	case notExpr:
+		return q.checkColumns(t, e.e)
+	case comparison:
+		for _, o := range []operand{e.left, e.right} {
+			if o.isColumn() && t.column(o.column) < 0 {
+				return fmt.Errorf("sqldriver: no such column %q in %s", o.column, q.table)
+			}
+		}
+	}
+	return nil
+}
+
+// rows are the rows of a query, read from the file as they are needed, or
+// from sorted when the query has an ORDER BY.
+type rows struct {
+	ctx     context.Context
+	table   *table
+	query   *query
+	args    []driver.Value
+	indexes []int // Indexes of the selected columns
+	limit   int   // -1 if none
+	count   int
+
+	file   *os.File
+	reader *gocsv.Reader
+	sorted [][]driver.Value
+}
+
+func (r *rows) Columns() []string {
+	columns := make([]string, len(r.indexes))
+	for i, index := range r.indexes {
+		columns[i] = r.table.columns[index]
+	}
+	return columns
+}
+
+func (r *rows) ColumnTypeDatabaseTypeName(index int) string {
+	return databaseTypeNames[r.table.types[r.indexes[index]]]
+}
+
+func (r *rows) ColumnTypeScanType(index int) reflect.Type {
+	return scanTypes[r.table.types[r.indexes[index]]]
+}
+
+func (r *rows) ColumnTypeNullable(index int) (nullable, ok bool) {
+	return r.table.types[r.indexes[index]] != typeString, true
+}
+
+func (r *rows) Close() error {
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.limit >= 0 && r.count >= r.limit {
+		return io.EOF
+	}
+	var values []driver.Value
+	if len(r.query.orderBy) > 0 {
+		if len(r.sorted) == 0 {
+			return io.EOF
+		}
+		values, r.sorted = r.sorted[0], r.sorted[1:]
+	} else {
+		var err error
+		if values, err = r.next(); err != nil {
+			return err
+		}
+	}
+	for i, index := range r.indexes {
+		dest[i] = values[index]
+	}
+	r.count++
+	return nil
+}
+
+// next reads the next record matching the condition.
+func (r *rows) next() ([]driver.Value, error) {
+	if r.file == nil {
+		return nil, io.EOF
+	}
+	for {
+		if err := r.ctx.Err(); err != nil {
+			return nil, err
+		}
+		record, err := r.reader.Read()
+		if err != nil {
+			return nil, err
+		}
+		values := make([]driver.Value, len(r.table.columns))
+		for i, typ := range r.table.types {
+			if i < len(record) {
+				values[i] = convert(record[i], typ)
+			}
+		}
+		if r.query.where == nil || r.matches(r.query.where, values) {
+			return values, nil
+		}
+	}
+}
+
+// sort reads all the matching records, in the order of the query.
+func (r *rows) sort() error {
+	r.sorted = [][]driver.Value{}
+	for {
+		values, err := r.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		r.sorted = append(r.sorted, values)
+	}
+	keys := make([]int, len(r.query.orderBy))
+	for i, item := range r.query.orderBy {
+		if keys[i] = r.table.column(item.column); keys[i] < 0 {
+			return fmt.Errorf("sqldriver: no such column %q in %s", item.column, r.query.table)
+		}
+	}
+	sort.SliceStable(r.sorted, func(i, j int) bool {
+		for k, item := range r.query.orderBy {
+			a, b := r.sorted[i][keys[k]], r.sorted[j][keys[k]]
+			c, ok := compare(a, b)
+			if !ok { // NULL first
+				switch {
+				case a == nil && b == nil:
+					continue
+				case a == nil:
+					c = -1
+				default:
+					c = 1
+				}
+			}
+			if c != 0 {
+				return (c < 0) != item.desc
+			}
+		}
+		return false
+	})
+	return nil
+}
+
+// truth is the value of a condition in the three-valued logic of SQL:
+// comparisons with NULL are unknown, and so is NOT unknown.
+type truth int
+
+const (
+	isFalse truth = iota
+	isTrue
+	isUnknown
+)
+
+// matches reports whether the record of values matches the condition e,
+// which must be true.
+func (r *rows) matches(e expr, values []driver.Value) bool {
+	return r.eval(e, values) == isTrue
+}
+
+// eval evaluates the condition for the values of a record.
+func (r *rows) eval(e expr, values []driver.Value) truth {
+	switch e := e.(type) {
+	case logicalExpr:
+		left, right := r.eval(e.left, values), r.eval(e.right, values)
+		decisive := isTrue // The value that decides an OR whatever the other
+		if e.and {
+			decisive = isFalse
+		}
+		switch {
+		case left == decisive || right == decisive:
+			return decisive
+		case left == isUnknown || right == isUnknown:
+			return isUnknown
+		}
+		return left // Both are the value that does not decide
+	case notExpr:
+		switch r.eval(e.e, values) {
+		case isTrue:
+			return isFalse
+		case isFalse:
+			return isTrue
+		}
+		return isUnknown
+	case comparison:
+		left := r.operand(e.left, values)
+		switch e.op {
+		case "IS NULL":
+			return truthOf(left == nil)
+		case "IS NOT NULL":
+			return truthOf(left != nil)
+		}
+		c, ok := compare(left, r.operand(e.right, values))
+		if !ok {
+			return isUnknown // A NULL
+		}
+		switch e.op {
+		case "=":
+			return truthOf(c == 0)
+		case "<>":
+			return truthOf(c != 0)
+		case "<":
+			return truthOf(c < 0)
+		case "<=":
+			return truthOf(c <= 0)
+		case ">":
+			return truthOf(c > 0)
+		case ">=":
+			return truthOf(c >= 0)
+		}
+	}
+	return isFalse
+}
+
+func truthOf(b bool) truth {
+	if b {
+		return isTrue
+	}
+	return isFalse
+}
+
+func (r *rows) operand(o operand, values []driver.Value) driver.Value {
+	switch {
+	case o.isColumn():
+		return values[r.table.column(o.column)]
+	case o.placeholder > 0:
+		if o.placeholder <= len(r.args) {
+			return r.args[o.placeholder-1]
+		}
+		return nil
+	}
+	return o.value
+}
@@ -0,0 +1,186 @@
+package sqldriver
+
+import (
+	"database/sql"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func openTestDB(t *testing.T) *sql.DB {
+	dir := t.TempDir()
+	files := map[string]string{
+		"clients.csv": "\uFEFFid,name,age,score,active\n" +
+			"1,Alice,34,9.5,true\n" +
+			"2,Bob,,7,false\n" +
+			"3,\"Smith, Carol\",28,8.25,true\n" +
+			"4,Dan,51,,false\n",
+		"empty.csv": "",
+		"notes.txt": "not a table",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db, err := sql.Open("gocsv", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func queryNames(t *testing.T, db *sql.DB, query string, args ...interface{}) []string {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		t.Fatalf("%s: %v", query, err)
+	}
+	defer rows.Close()
+	names := []string{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return names
+}
+
+func TestQuery(t *testing.T) {
+	db := openTestDB(t)
+	tests := []struct {
+		query string
+		args  []interface{}
+		want  []string
+	}{
+		{"SELECT name FROM clients", nil, []string{"Alice", "Bob", "Smith, Carol", "Dan"}},
+		{"SELECT name FROM clients WHERE age > 30", nil, []string{"Alice", "Dan"}},
+		{"SELECT name FROM clients WHERE age >= ? AND active = ?", []interface{}{30, true}, []string{"Alice"}},
+		{"SELECT name FROM clients WHERE name = 'Bob' OR score > 9", nil, []string{"Alice", "Bob"}},
+		{"select name from clients where not (active = true) and age is null", nil, []string{"Bob"}},
+		{"SELECT name FROM clients WHERE score IS NOT NULL AND id <> 1", nil, []string{"Bob", "Smith, Carol"}},
+		{"SELECT name FROM clients WHERE name < 'C'", nil, []string{"Alice", "Bob"}},
+		{"SELECT name FROM clients ORDER BY age DESC", nil, []string{"Dan", "Alice", "Smith, Carol", "Bob"}},
+		{"SELECT name FROM clients ORDER BY active, name", nil, []string{"Bob", "Dan", "Alice", "Smith, Carol"}},
+		{"SELECT name FROM clients LIMIT 2", nil, []string{"Alice", "Bob"}},
+		{"SELECT name FROM clients ORDER BY score LIMIT ?", []interface{}{2}, []string{"Dan", "Bob"}},
+		{`SELECT "name" FROM clients WHERE score = 8.25;`, nil, []string{"Smith, Carol"}},
+		{"SELECT name FROM clients WHERE age > 100", nil, []string{}},
+		{"SELECT name FROM clients WHERE age > -1 AND score > -.5", nil, []string{"Alice", "Smith, Carol"}},
+		{"SELECT name FROM clients WHERE NOT (age = 34)", nil, []string{"Smith, Carol", "Dan"}},
+		{"SELECT name FROM clients WHERE NOT (age > 30 AND score > 8)", nil, []string{"Bob", "Smith, Carol"}},
+		{"SELECT name FROM clients WHERE NOT (age < 30 OR score = 7)", nil, []string{"Alice"}},
+		{"SELECT name FROM clients WHERE NOT (NULL = 1)", nil, []string{}},
+		{"SELECT name FROM clients WHERE NOT NOT (age = ?)", []interface{}{nil}, []string{}},
+	}
+	for _, test := range tests {
+		if got := queryNames(t, db, test.query, test.args...); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: got %q, wanted %q", test.query, got, test.want)
+		}
+	}
+}
+
+func TestQuery_types(t *testing.T) {
+	db := openTestDB(t)
+	rows, err := db.Query("SELECT * FROM clients WHERE id = 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.ColumnTypes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names, types []string
+	for _, c := range columns {
+		names = append(names, c.Name())
+		types = append(types, c.DatabaseTypeName())
+	}
+	if want := []string{"id", "name", "age", "score", "active"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("got columns %q, wanted %q", names, want)
+	}
+	if want := []string{"INTEGER", "TEXT", "INTEGER", "REAL", "BOOLEAN"}; !reflect.DeepEqual(types, want) {
+		t.Errorf("got types %q, wanted %q", types, want)
+	}
+
+	if !rows.Next() {
+		t.Fatal("no row")
+	}
+	var (
+		id     int64
+		name   string
+		age    sql.NullInt64
+		score  float64
+		active bool
+	)
+	if err := rows.Scan(&id, &name, &age, &score, &active); err != nil {
+		t.Fatal(err)
+	}
+	if id != 2 || name != "Bob" || age.Valid || score != 7 || active {
+		t.Errorf("got %v %v %v %v %v", id, name, age, score, active)
+	}
+	if rows.Next() {
+		t.Error("more than one row")
+	}
+}
+
+func TestQuery_errors(t *testing.T) {
+	db := openTestDB(t)
+	for _, query := range []string{
+		"SELECT name FROM missing",
+		"SELECT nope FROM clients",
+		"SELECT name FROM clients WHERE nope = 1",
+		"SELECT name FROM clients ORDER BY nope",
+		"SELECT name FROM empty",
+		"SELECT name FROM notes",
+		"SELECT name FROM clients WHERE",
+		"SELECT name FROM clients LIMIT 'a'",
+		"SELECT name, FROM clients",
+		"SELECT name FROM clients WHERE name = 'Bob",
+		"DELETE FROM clients",
+	} {
+		if rows, err := db.Query(query); err == nil {
+			rows.Close()
+			t.Errorf("%s: expected an error", query)
+		}
+	}
+	if _, err := db.Exec("SELECT name FROM clients"); err != ErrReadOnly {
+		t.Errorf("Exec: got %v, wanted ErrReadOnly", err)
+	}
+	if _, err := db.Begin(); err != ErrReadOnly {
+		t.Errorf("Begin: got %v, wanted ErrReadOnly", err)
+	}
+	db2, _ := sql.Open("gocsv", filepath.Join(t.TempDir(), "missing"))
+	if err := db2.Ping(); err == nil {
+		t.Error("Ping: expected an error for a missing directory")
+	}
+}
+
+func TestQuery_changedFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "t.csv")
+	if err := os.WriteFile(path, []byte("v\n1\n2\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("gocsv", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if got := queryNames(t, db, "SELECT v FROM t WHERE v < 10"); !reflect.DeepEqual(got, []string{"1", "2"}) {
+		t.Errorf("got %q", got)
+	}
+	if err := os.WriteFile(path, []byte("v\n1\nten\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got := queryNames(t, db, "SELECT v FROM t ORDER BY v DESC"); !reflect.DeepEqual(got, []string{"ten", "1"}) {
+		t.Errorf("got %q", got)
+	}
+}
@@ -0,0 +1,427 @@
+package sqldriver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// query is a parsed SELECT statement.
+type query struct {
+	columns []string // nil for *
+	table   string
+	where   expr // nil if none
+	orderBy []orderItem
+	limit   *operand // nil if none
+	inputs  int      // Number of placeholders
+}
+
+type orderItem struct {
+	column string
+	desc   bool
+}
+
+// expr is a condition of the WHERE clause.
+type expr interface{}
+
+// logicalExpr is an AND or an OR of two conditions.
+type logicalExpr struct {
+	and         bool
+	left, right expr
+}
+
+type notExpr struct {
+	e expr
+}
+
+// comparison compares two operands, op being one of = <> < <= > >=, or
+// tests if left is NULL, op being "IS NULL" or "IS NOT NULL".
+type comparison struct {
+	op          string
+	left, right operand
+}
+
+// operand is a column, a literal value or a placeholder.
+type operand struct {
+	column      string
+	value       interface{} // int64, float64, string, bool or nil
+	placeholder int         // Ordinal of the placeholder, from 1, 0 if none
+}
+
+func (o operand) isColumn() bool {
+	return o.column != ""
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenQuotedIdent
+	tokenNumber
+	tokenString
+	tokenSymbol
+	tokenPlaceholder
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func lex(s string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case c == '_' || unicode.IsLetter(c):
+			j := i + 1
+			for j < len(s) && (s[j] == '_' || s[j] == '.' || unicode.IsLetter(rune(s[j])) || unicode.IsDigit(rune(s[j]))) {
+				j++
+			}
+			tokens = append(tokens, token{tokenIdent, s[i:j], i})
+			i = j
+		case isNumberStart(s[i:]) || (c == '-' && isNumberStart(s[i+1:])):
+			j := i + 1
+			for j < len(s) && (unicode.IsDigit(rune(s[j])) || s[j] == '.' || s[j] == 'e' || s[j] == 'E' ||
+				((s[j] == '+' || s[j] == '-') && (s[j-1] == 'e' || s[j-1] == 'E'))) {
+				j++
+			}
+			tokens = append(tokens, token{tokenNumber, s[i:j], i})
+			i = j
+		case c == '\'' || c == '"' || c == '`':
+			text, n, err := lexQuoted(s[i:], byte(c))
+			if err != nil {
+				return nil, fmt.Errorf("sqldriver: %v at %d", err, i)
+			}
+			kind := tokenQuotedIdent
+			if c == '\'' {
+				kind = tokenString
+			}
+			tokens = append(tokens, token{kind, text, i})
+			i += n
+		case c == '?':
+			tokens = append(tokens, token{tokenPlaceholder, "?", i})
+			i++
+		case strings.HasPrefix(s[i:], "<=") || strings.HasPrefix(s[i:], ">=") ||
+			strings.HasPrefix(s[i:], "<>") || strings.HasPrefix(s[i:], "!="):
+			tokens = append(tokens, token{tokenSymbol, s[i : i+2], i})
+			i += 2
+		case strings.ContainsRune("=<>(),*;", c):
+			tokens = append(tokens, token{tokenSymbol, s[i : i+1], i})
+			i++
+		default:
+			return nil, fmt.Errorf("sqldriver: unexpected %q at %d", c, i)
+		}
+	}
+	return append(tokens, token{tokenEOF, "", len(s)}), nil
+}
+
+// isNumberStart reports whether s starts with a number, a digit or a dot
+// followed by a digit. A minus sign before it makes the number negative.
+func isNumberStart(s string) bool {
+	if len(s) > 1 && s[0] == '.' {
+		s = s[1:]
+	}
+	return len(s) > 0 && '0' <= s[0] && s[0] <= '9'
+}
+
+// lexQuoted returns the text quoted at the start of s, in which two quotes
+// stand for one, and the length of the quoted text.
+func lexQuoted(s string, quote byte) (string, int, error) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		if s[i] != quote {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+1 < len(s) && s[i+1] == quote {
+			b.WriteByte(quote)
+			i++
+			continue
+		}
+		return b.String(), i + 1, nil
+	}
+	return "", 0, fmt.Errorf("unterminated %c", quote)
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+	inputs int
+}
+
+// parse parses a SELECT statement of the supported subset of SQL:
+//
+//	SELECT * | column [, ...] FROM table
+//	[WHERE condition] [ORDER BY column [ASC | DESC] [, ...]] [LIMIT count]
+//
+// where the conditions compare columns, literals and ? placeholders with
+// = <> != < <= > >= or IS [NOT] NULL, combined with AND, OR, NOT and parentheses.
+func parse(s string) (*query, error) {
+	tokens, err := lex(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	q, err := p.parseSelect()
+	if err != nil {
+		return nil, fmt.Errorf("sqldriver: %v", err)
+	}
+	q.inputs = p.inputs
+	return q, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+// keyword consumes the next token if it is one of the keywords.
+func (p *parser) keyword(keywords ...string) bool {
+	t := p.peek()
+	if t.kind != tokenIdent {
+		return false
+	}
+	for _, k := range keywords {
+		if strings.EqualFold(t.text, k) {
+			p.pos++
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) symbol(s string) bool {
+	if t := p.peek(); t.kind == tokenSymbol && t.text == s {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *parser) unexpected(expected string) error {
+	t := p.peek()
+	if t.kind == tokenEOF {
+		return fmt.Errorf("expected %s at the end of the query", expected)
+	}
+	return fmt.Errorf("expected %s at %d, got %q", expected, t.pos, t.text)
+}
+
+var reserved = map[string]bool{
+	"SELECT": true, "FROM": true, "WHERE": true, "ORDER": true, "BY": true, "LIMIT": true,
+	"AND": true, "OR": true, "NOT": true, "IS": true, "NULL": true, "ASC": true, "DESC": true,
+	"TRUE": true, "FALSE": true,
+}
+
+func (p *parser) identifier(what string) (string, error) {
+	t := p.peek()
+	if t.kind == tokenQuotedIdent || (t.kind == tokenIdent && !reserved[strings.ToUpper(t.text)]) {
+		p.pos++
+		return t.text, nil
+	}
+	return "", p.unexpected(what)
+}
+
+func (p *parser) parseSelect() (*query, error) {
+	q := &query{}
+	if !p.keyword("SELECT") {
+		return nil, p.unexpected("SELECT")
+	}
+	if !p.symbol("*") {
+		for {
+			column, err := p.identifier("a column")
+			if err != nil {
+				return nil, err
+			}
+			q.columns = append(q.columns, column)
+			if !p.symbol(",") {
+				break
+			}
+		}
+	}
+	if !p.keyword("FROM") {
+		return nil, p.unexpected("FROM")
+	}
+	var err error
+	if q.table, err = p.identifier("a table"); err != nil {
+		return nil, err
+	}
+	if p.keyword("WHERE") {
+		if q.where, err = p.parseOr(); err != nil {
+			return nil, err
+		}
+	}
+	if p.keyword("ORDER") {
+		if !p.keyword("BY") {
+			return nil, p.unexpected("BY")
+		}
+		for {
+			column, err := p.identifier("a column")
+			if err != nil {
+				return nil, err
+			}
+			item := orderItem{column: column}
+			if p.keyword("DESC") {
+				item.desc = true
+			} else {
+				p.keyword("ASC")
+			}
+			q.orderBy = append(q.orderBy, item)
+			if !p.symbol(",") {
+				break
+			}
+		}
+	}
+	if p.keyword("LIMIT") {
+		limit, err := p.parseOperand()
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := limit.value.(int64); !ok && limit.placeholder == 0 {
+			return nil, fmt.Errorf("LIMIT must be an integer or a placeholder")
+		}
+		q.limit = &limit
+	}
+	p.symbol(";")
+	if p.peek().kind != tokenEOF {
+		return nil, p.unexpected("the end of the query")
+	}
+	return q, nil
+}
+
+func (p *parser) parseOr() (expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("OR") {
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = logicalExpr{and: false, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (expr, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("AND") {
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = logicalExpr{and: true, left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (expr, error) {
+	if p.keyword("NOT") {
+		e, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notExpr{e}, nil
+	}
+	if p.symbol("(") {
+		e, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if !p.symbol(")") {
+			return nil, p.unexpected(")")
+		}
+		return e, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *parser) parseComparison() (expr, error) {
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	if p.keyword("IS") {
+		op := "IS NULL"
+		if p.keyword("NOT") {
+			op = "IS NOT NULL"
+		}
+		if !p.keyword("NULL") {
+			return nil, p.unexpected("NULL")
+		}
+		return comparison{op: op, left: left}, nil
+	}
+	t := p.peek()
+	switch t.text {
+	case "=", "<>", "!=", "<", "<=", ">", ">=":
+		if t.kind == tokenSymbol {
+			break
+		}
+		fallthrough
+	default:
+		return nil, p.unexpected("a comparison")
+	}
+	p.pos++
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	op := t.text
+	if op == "!=" {
+		op = "<>"
+	}
+	return comparison{op: op, left: left, right: right}, nil
+}
+
+func (p *parser) parseOperand() (operand, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokenPlaceholder:
+		p.pos++
+		p.inputs++
+		return operand{placeholder: p.inputs}, nil
+	case tokenString:
+		p.pos++
+		return operand{value: t.text}, nil
+	case tokenNumber:
+		p.pos++
+		if i, err := strconv.ParseInt(t.text, 10, 64); err == nil {
+			return operand{value: i}, nil
+		}
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return operand{}, fmt.Errorf("invalid number %q at %d", t.text, t.pos)
+		}
+		return operand{value: f}, nil
+	case tokenIdent:
+		switch {
+		case p.keyword("NULL"):
+			return operand{}, nil
+		case p.keyword("TRUE"):
+			return operand{value: true}, nil
+		case p.keyword("FALSE"):
+			return operand{value: false}, nil
+		}
+	}
+	column, err := p.identifier("a column or a value")
+	if err != nil {
+		return operand{}, err
+	}
+	return operand{column: column}, nil
+}
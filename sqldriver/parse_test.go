@@ -0,0 +1,42 @@
+package sqldriver
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse_negativeNumbers(t *testing.T) {
+	q, err := parse("SELECT * FROM t WHERE x > -1 AND y = -.5 AND z <> -2e-3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var values []interface{}
+	for e := q.where; e != nil; {
+		and, ok := e.(logicalExpr)
+		if !ok {
+			values = append(values, e.(comparison).right.value)
+			break
+		}
+		values = append(values, and.right.(comparison).right.value)
+		e = and.left
+	}
+	if len(values) != 3 || values[0] != -2e-3 || values[1] != -.5 || values[2] != int64(-1) {
+		t.Errorf("got values %v", values)
+	}
+	if _, err := parse("SELECT * FROM t WHERE x > - 1"); err == nil {
+		t.Error("expected an error for a minus sign apart from its number")
+	}
+}
+
+func TestParse_endOfQuery(t *testing.T) {
+	for _, query := range []string{"SELECT * FROM t WHERE x", "SELECT * FROM t WHERE (x"} {
+		_, err := parse(query)
+		if err == nil || !strings.Contains(err.Error(), "expected a comparison at the end of the query") {
+			t.Errorf("%s: got %v", query, err)
+		}
+	}
+	_, err := parse("SELECT * FROM t WHERE x y")
+	if err == nil || !strings.Contains(err.Error(), `expected a comparison at 24, got "y"`) {
+		t.Errorf("got %v", err)
+	}
+}
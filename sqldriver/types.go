@@ -0,0 +1,257 @@
+package sqldriver
+
+import (
+	"database/sql/driver"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gocarina/gocsv"
+)
+
+// columnType is the type of the values of a column, inferred from all of them.
+type columnType int
+
+const (
+	typeUnknown columnType = iota // No value seen yet
+	typeInt
+	typeFloat
+	typeBool
+	typeString
+)
+
+// databaseTypeNames are the names reported by ColumnTypeDatabaseTypeName.
+var databaseTypeNames = map[columnType]string{
+	typeInt:    "INTEGER",
+	typeFloat:  "REAL",
+	typeBool:   "BOOLEAN",
+	typeString: "TEXT",
+}
+
+var scanTypes = map[columnType]reflect.Type{
+	typeInt:    reflect.TypeOf(int64(0)),
+	typeFloat:  reflect.TypeOf(float64(0)),
+	typeBool:   reflect.TypeOf(false),
+	typeString: reflect.TypeOf(""),
+}
+
+func valueType(s string) columnType {
+	if _, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return typeInt
+	}
+	if _, err := strconv.ParseFloat(s, 64); err == nil {
+		return typeFloat
+	}
+	if _, err := strconv.ParseBool(s); err == nil {
+		return typeBool
+	}
+	return typeString
+}
+
+func mergeTypes(a, b columnType) columnType {
+	switch {
+	case a == typeUnknown || a == b:
+		return b
+	case (a == typeInt && b == typeFloat) || (a == typeFloat && b == typeInt):
+		return typeFloat
+	}
+	return typeString
+}
+
+// convert returns the value of a column of type t. Empty values are NULL,
+// but in TEXT columns.
+func convert(s string, t columnType) driver.Value {
+	switch t {
+	case typeInt:
+		if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return i
+		}
+	case typeFloat:
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return f
+		}
+	case typeBool:
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b
+		}
+	default:
+		return s
+	}
+	return nil
+}
+
+// table is the header and the column types of a CSV file.
+type table struct {
+	columns []string
+	types   []columnType
+	modTime time.Time
+	size    int64
+}
+
+// column returns the index of the named column, compared without case if
+// no column has the exact name, or -1.
+func (t *table) column(name string) int {
+	for i, c := range t.columns {
+		if c == name {
+			return i
+		}
+	}
+	for i, c := range t.columns {
+		if strings.EqualFold(c, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+var (
+	tablesMu sync.Mutex
+	tables   = make(map[string]*table)
+)
+
+// loadTable returns the table of the file at path, reading the whole file
+// to infer the column types, unless it did not change since the last time.
+func loadTable(path string) (*table, error) {
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	tablesMu.Lock()
+	t, ok := tables[path]
+	tablesMu.Unlock()
+	if ok && t.modTime.Equal(stat.ModTime()) && t.size == stat.Size() {
+		return t, nil
+	}
+
+	f, reader, err := openTable(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, gocsv.ErrEmptyCSV
+	}
+	if err != nil {
+		return nil, err
+	}
+	t = &table{
+		columns: append([]string(nil), header...),
+		types:   make([]columnType, len(header)),
+		modTime: stat.ModTime(),
+		size:    stat.Size(),
+	}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		for i, value := range record {
+			if i < len(t.types) && value != "" {
+				t.types[i] = mergeTypes(t.types[i], valueType(value))
+			}
+		}
+	}
+	for i, typ := range t.types {
+		if typ == typeUnknown {
+			t.types[i] = typeString
+		}
+	}
+	tablesMu.Lock()
+	tables[path] = t
+	tablesMu.Unlock()
+	return t, nil
+}
+
+// openTable opens the file at path, with a reader of its records.
+func openTable(path string) (*os.File, *gocsv.Reader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	reader := gocsv.NewReader(gocsv.NewDecodingReader(f, gocsv.UTF8, false))
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+	return f, reader, nil
+}
+
+// compare compares two values, numbers with numbers and other values as
+// strings. It returns false if one of the values is NULL.
+func compare(a, b driver.Value) (int, bool) {
+	a, b = normalize(a), normalize(b)
+	if a == nil || b == nil {
+		return 0, false
+	}
+	if x, ok := a.(int64); ok {
+		if y, ok := b.(int64); ok {
+			return compareOrdered(x, y), true
+		}
+	}
+	if x, ok := number(a); ok {
+		if y, ok := number(b); ok {
+			return compareOrdered(x, y), true
+		}
+	}
+	return strings.Compare(toString(a), toString(b)), true
+}
+
+func compareOrdered[T int64 | float64](x, y T) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+func normalize(v driver.Value) driver.Value {
+	switch v := v.(type) {
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	}
+	return v
+}
+
+// number returns v as a float64, parsing strings and counting booleans as 0
+// and 1. Pairs of int64 are compared apart, without losing precision.
+func number(v driver.Value) (float64, bool) {
+	switch v := v.(type) {
+	case int64:
+		return float64(v), true
+	case float64:
+		return v, true
+	case bool:
+		if v {
+			return 1, true
+		}
+		return 0, true
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+func toString(v driver.Value) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	return ""
+}
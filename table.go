@@ -0,0 +1,232 @@
+package gocsv
+
+import (
+	"bufio"
+	"html"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// TableFormat is a format of human-readable tables.
+type TableFormat int
+
+const (
+	// TextTable is plain text, in aligned columns under a dashed header.
+	TextTable TableFormat = iota
+	// MarkdownTable is a GitHub Flavored Markdown table.
+	MarkdownTable
+	// HTMLTable is an HTML table element.
+	HTMLTable
+)
+
+// TableOptions configures the tables written by a TableWriter.
+type TableOptions struct {
+	// MaxWidth is the maximum number of characters of the values, the longer
+	// ones are truncated with an ellipsis. Zero means no limit.
+	MaxWidth int
+	// AlignNumbers right-aligns the columns of which all values are numbers.
+	AlignNumbers bool
+	// RawHTML writes the values of HTML tables as they are, instead of escaping them.
+	RawHTML bool
+}
+
+// MarshalTable writes the interface as a human-readable table in the writer,
+// with the header and values written by Marshal.
+func MarshalTable(in interface{}, out io.Writer, format TableFormat, opts TableOptions) error {
+	return writeTo(NewSafeWriter(NewTableWriter(out, format, opts)), in, false)
+}
+
+// TableWriter is a CSVWriter writing the records as a human-readable table,
+// the first record being the header. The records are kept until Flush, that
+// writes them as a table, so as to align the columns.
+type TableWriter struct {
+	format  TableFormat
+	opts    TableOptions
+	w       *bufio.Writer
+	records [][]string
+}
+
+// NewTableWriter returns a new TableWriter that writes tables to out.
+func NewTableWriter(out io.Writer, format TableFormat, opts TableOptions) *TableWriter {
+	return &TableWriter{format: format, opts: opts, w: bufio.NewWriter(out)}
+}
+
+// Write keeps a record, until Flush writes it in the table.
+func (t *TableWriter) Write(record []string) error {
+	row := make([]string, len(record))
+	for i, value := range record {
+		row[i] = t.truncate(value)
+	}
+	t.records = append(t.records, row)
+	return nil
+}
+
+// Flush writes the records written since the last Flush as a table.
+// To check if an error occurred during the Flush, call Error.
+func (t *TableWriter) Flush() {
+	if len(t.records) > 0 {
+		switch t.format {
+		case MarkdownTable:
+			t.writeMarkdown()
+		case HTMLTable:
+			t.writeHTML()
+		default:
+			t.writeText()
+		}
+		t.records = nil
+	}
+	t.w.Flush()
+}
+
+// Error reports any error that has occurred during a previous Flush.
+func (t *TableWriter) Error() error {
+	_, err := t.w.Write(nil)
+	return err
+}
+
+func (t *TableWriter) truncate(value string) string {
+	if t.opts.MaxWidth <= 0 || utf8.RuneCountInString(value) <= t.opts.MaxWidth {
+		return value
+	}
+	runes := []rune(value)
+	return string(runes[:t.opts.MaxWidth-1]) + "…"
+}
+
+// layout returns the width of each column and whether it is right-aligned.
+func (t *TableWriter) layout() (widths []int, right []bool) {
+	for _, record := range t.records {
+		for i, value := range record {
+			if i >= len(widths) {
+				widths = append(widths, 0)
+				right = append(right, t.opts.AlignNumbers)
+			}
+			if n := utf8.RuneCountInString(value); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+	numbers := make([]bool, len(widths))
+	for _, record := range t.records[1:] {
+		for i, value := range record {
+			if value != "" {
+				numbers[i] = true
+				right[i] = right[i] && isNumeric(value)
+			}
+		}
+	}
+	for i := range right {
+		right[i] = right[i] && numbers[i]
+	}
+	return widths, right
+}
+
+// pad returns the value padded to width, on the left if right.
+func pad(value string, width int, right bool) string {
+	spaces := strings.Repeat(" ", width-utf8.RuneCountInString(value))
+	if right {
+		return spaces + value
+	}
+	return value + spaces
+}
+
+var textReplacer = strings.NewReplacer("\r\n", " ", "\n", " ", "\r", " ")
+
+func (t *TableWriter) writeText() {
+	widths, right := t.layout()
+	writeLine := func(values []string) {
+		var b strings.Builder
+		for i, width := range widths {
+			value := ""
+			if i < len(values) {
+				value = textReplacer.Replace(values[i])
+			}
+			if i > 0 {
+				b.WriteString("  ")
+			}
+			b.WriteString(pad(value, width, right[i]))
+		}
+		t.w.WriteString(strings.TrimRight(b.String(), " ") + "\n")
+	}
+	writeLine(t.records[0])
+	dashes := make([]string, len(widths))
+	for i, width := range widths {
+		dashes[i] = strings.Repeat("-", width)
+	}
+	writeLine(dashes)
+	for _, record := range t.records[1:] {
+		writeLine(record)
+	}
+}
+
+var markdownReplacer = strings.NewReplacer("|", `\|`, "\r\n", "<br>", "\n", "<br>", "\r", "<br>")
+
+func (t *TableWriter) writeMarkdown() {
+	records := make([][]string, len(t.records))
+	for i, record := range t.records {
+		records[i] = make([]string, len(record))
+		for j, value := range record {
+			records[i][j] = markdownReplacer.Replace(value)
+		}
+	}
+	t.records = records
+	widths, right := t.layout()
+	for i, width := range widths {
+		if width < 3 { // The shortest delimiter row cell
+			widths[i] = 3
+		}
+	}
+	writeLine := func(values []string) {
+		t.w.WriteString("|")
+		for i, width := range widths {
+			value := ""
+			if i < len(values) {
+				value = values[i]
+			}
+			t.w.WriteString(" " + pad(value, width, right[i]) + " |")
+		}
+		t.w.WriteString("\n")
+	}
+	writeLine(t.records[0])
+	t.w.WriteString("|")
+	for i, width := range widths {
+		if right[i] {
+			t.w.WriteString(" " + strings.Repeat("-", width-1) + ": |")
+		} else {
+			t.w.WriteString(" " + strings.Repeat("-", width) + " |")
+		}
+	}
+	t.w.WriteString("\n")
+	for _, record := range t.records[1:] {
+		writeLine(record)
+	}
+}
+
+func (t *TableWriter) writeHTML() {
+	widths, right := t.layout()
+	writeRow := func(values []string, cell string) {
+		t.w.WriteString("<tr>")
+		for i := range widths {
+			value := ""
+			if i < len(values) {
+				value = values[i]
+			}
+			if !t.opts.RawHTML {
+				value = html.EscapeString(value)
+			}
+			if right[i] && cell == "td" {
+				t.w.WriteString(`<td style="text-align: right">` + value + "</td>")
+			} else {
+				t.w.WriteString("<" + cell + ">" + value + "</" + cell + ">")
+			}
+		}
+		t.w.WriteString("</tr>\n")
+	}
+	t.w.WriteString("<table>\n<thead>\n")
+	writeRow(t.records[0], "th")
+	t.w.WriteString("</thead>\n<tbody>\n")
+	for _, record := range t.records[1:] {
+		writeRow(record, "td")
+	}
+	t.w.WriteString("</tbody>\n</table>\n")
+}
@@ -0,0 +1,75 @@
+package gocsv
+
+import (
+	"bytes"
+	"testing"
+)
+
+type tableSample struct {
+	Name  string  `csv:"name"`
+	Count int     `csv:"count"`
+	Note  string  `csv:"note"`
+	Ratio float64 `csv:"ratio"`
+}
+
+var tableSamples = []tableSample{
+	{Name: "a <b>", Count: 10, Note: "short", Ratio: 0.5},
+	{Name: "éé", Count: -2, Note: "a long | note\nwrapped", Ratio: 12},
+}
+
+func TestMarshalTable(t *testing.T) {
+	tests := []struct {
+		name     string
+		format   TableFormat
+		opts     TableOptions
+		expected string
+	}{{
+		name: "text",
+		opts: TableOptions{AlignNumbers: true, MaxWidth: 10},
+		expected: "" +
+			"name   count  note        ratio\n" +
+			"-----  -----  ----------  -----\n" +
+			"a <b>     10  short         0.5\n" +
+			"éé        -2  a long | …     12\n",
+	}, {
+		name:   "markdown",
+		format: MarkdownTable,
+		opts:   TableOptions{AlignNumbers: true},
+		expected: "" +
+			"| name  | count | note                      | ratio |\n" +
+			"| ----- | ----: | ------------------------- | ----: |\n" +
+			"| a <b> |    10 | short                     |   0.5 |\n" +
+			"| éé    |    -2 | a long \\| note<br>wrapped |    12 |\n",
+	}, {
+		name:   "html",
+		format: HTMLTable,
+		opts:   TableOptions{AlignNumbers: true},
+		expected: "<table>\n<thead>\n" +
+			"<tr><th>name</th><th>count</th><th>note</th><th>ratio</th></tr>\n" +
+			"</thead>\n<tbody>\n" +
+			"<tr><td>a &lt;b&gt;</td><td style=\"text-align: right\">10</td><td>short</td><td style=\"text-align: right\">0.5</td></tr>\n" +
+			"<tr><td>éé</td><td style=\"text-align: right\">-2</td><td>a long | note\nwrapped</td><td style=\"text-align: right\">12</td></tr>\n" +
+			"</tbody>\n</table>\n",
+	}, {
+		name:   "raw html",
+		format: HTMLTable,
+		opts:   TableOptions{RawHTML: true, MaxWidth: 4},
+		expected: "<table>\n<thead>\n" +
+			"<tr><th>name</th><th>cou…</th><th>note</th><th>rat…</th></tr>\n" +
+			"</thead>\n<tbody>\n" +
+			"<tr><td>a <…</td><td>10</td><td>sho…</td><td>0.5</td></tr>\n" +
+			"<tr><td>éé</td><td>-2</td><td>a l…</td><td>12</td></tr>\n" +
+			"</tbody>\n</table>\n",
+	}}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := bytes.Buffer{}
+			if err := MarshalTable(tableSamples, &b, test.format, test.opts); err != nil {
+				t.Fatal(err)
+			}
+			if b.String() != test.expected {
+				t.Fatalf("expected\n%s\ngot\n%s", test.expected, b.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+package gocsv
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// Transform reads the CSV from r and writes it to w, one row at a time. Each
+// row is decoded to a T and passed to f, which can change it: the row is then
+// written with the columns of the fields of T that f changed encoded again,
+// and the other columns as they were read, in their positions. The rows for
+// which f returns false are not written. The header is written as it was
+// read: the fields of T without a column, when FailIfUnmatchedStructTags is
+// false, are not written. Two columns mapped to the same field are an error.
+//
+// The decoding hooks of T are called before f, BeforeMarshalCSV after it, and
+// the rules of the csv tags of T are checked on the values read and on the
+// values written. An error of f is returned as a *RowError.
+func Transform[T any](r io.Reader, w io.Writer, f func(*T) (keep bool, err error)) error {
+	outInnerType := reflect.TypeOf((*T)(nil)).Elem()
+	if err := ensureOutInnerType(outInnerType); err != nil {
+		return err
+	}
+	reader := getCSVReader(r)
+	headers, err := reader.Read()
+	if err == io.EOF {
+		return ErrEmptyCSV
+	} else if err != nil {
+		return err
+	}
+	headers = append([]string(nil), headers...) // The reader can reuse its records
+	// The decoder trims the BOM of its headers, the one written keeps it
+	rd, err := newRowDecoder(true, outInnerType, append([]string(nil), headers...), nil)
+	if err != nil {
+		return err
+	}
+	mapped := make(map[string]int, len(rd.csvHeadersLabels)) // Column of each field, by index chain
+	for j := range headers {
+		if fieldInfo, ok := rd.csvHeadersLabels[j]; ok {
+			index := fmt.Sprint(fieldInfo.IndexChain)
+			if k, ok := mapped[index]; ok {
+				return fmt.Errorf("gocsv: columns %d and %d are mapped to the same field %s", k+1, j+1, fieldInfo.getFirstKey())
+			}
+			mapped[index] = j
+		}
+	}
+	writer := getCSVWriter(w)
+	columns := rd.columns(len(headers))
+	if err := writer.writeColumns(headers, columns); err != nil {
+		return err
+	}
+
+	before := make(map[int]string, len(rd.csvHeadersLabels))
+	for i := 0; ; i++ {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		outInner, err := rd.decodeRow(row, i)
+		if err != nil {
+			return err
+		}
+		for j, fieldInfo := range rd.csvHeadersLabels {
+			if before[j], err = getInnerField(outInner, true, fieldInfo.IndexChain); err != nil {
+				return err
+			}
+		}
+		keep, err := f(outInner.Interface().(*T))
+		if err != nil {
+			return &RowError{Line: i + 2, Err: err}
+		}
+		if !keep {
+			continue
+		}
+		if _, err := beforeMarshal(outInner, i+2); err != nil {
+			return err
+		}
+		out := append([]string(nil), row...)
+		for j, fieldInfo := range rd.csvHeadersLabels {
+			if j >= len(out) {
+				continue // Short row, the column is not written
+			}
+			value, err := getInnerField(outInner, true, fieldInfo.IndexChain)
+			if err != nil {
+				return err
+			}
+			if value == before[j] {
+				continue // Unchanged, the cell is written as it was read
+			}
+			if err := fieldInfo.checkRules(value); err != nil {
+				return &RowError{Line: i + 2, Err: err}
+			}
+			out[j] = value
+		}
+		if err := writer.writeColumns(out, columns); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// columns returns the columnPolicy of the n columns of the CSV: which are
+// always quoted, nil if none, and the kinds of their fields, reflect.Invalid
+// for the columns not mapped to a field.
+func (rd *rowDecoder) columns(n int) columnPolicy {
+	var quoted []bool
+	kinds := make([]reflect.Kind, n)
+	for j, fieldInfo := range rd.csvHeadersLabels {
+		if j >= n {
+			continue
+		}
+		if fieldInfo.quote {
+			if quoted == nil {
+				quoted = make([]bool, n)
+			}
+			quoted[j] = true
+		}
+		kinds[j] = fieldInfo.kind(rd.outInnerType)
+	}
+	return columnPolicy{quoted: quoted, kinds: kinds}
+}
@@ -0,0 +1,132 @@
+package gocsv
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type transformedPrice struct {
+	SKU   string  `csv:"sku"`
+	Price float64 `csv:"price,min=0"`
+	Note  string  `csv:"note"`
+}
+
+func TestTransform(t *testing.T) {
+	in := "id,sku,label,price,note,stock\n" +
+		"1,ABC-1,\"Big, red\",10,,5\n" +
+		"2,ABC-2,Small,20,old,0\n" +
+		"3,ABC-3,Blue,30,,7\n"
+	var out bytes.Buffer
+	err := Transform(strings.NewReader(in), &out, func(p *transformedPrice) (bool, error) {
+		if p.SKU == "ABC-2" {
+			return false, nil
+		}
+		p.Price *= 1.1
+		p.Note = "raised"
+		return true, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "id,sku,label,price,note,stock\n" +
+		"1,ABC-1,\"Big, red\",11,raised,5\n" +
+		"3,ABC-3,Blue,33,raised,7\n"
+	if out.String() != expected {
+		t.Errorf("got\n%s\nwanted\n%s", out.String(), expected)
+	}
+}
+
+func TestTransform_unchanged(t *testing.T) {
+	type record struct {
+		Name string  `csv:"name"`
+		N    int     `csv:"n"`
+		F    float64 `csv:"f"`
+	}
+	in := "name,n,f\na,,1.50\nb,007,2e1\n"
+	var out bytes.Buffer
+	err := Transform(strings.NewReader(in), &out, func(r *record) (bool, error) {
+		if r.Name == "a" {
+			r.N++
+		}
+		return true, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "name,n,f\na,1,1.50\nb,007,2e1\n"; out.String() != expected {
+		t.Errorf("got %q, wanted %q", out.String(), expected)
+	}
+
+	defer func(fail bool) { FailIfDoubleHeaderNames = fail }(FailIfDoubleHeaderNames)
+	FailIfDoubleHeaderNames = false
+	err = Transform(strings.NewReader("name,n,f,name\na,,1.50,b\n"), &bytes.Buffer{}, func(*record) (bool, error) {
+		return true, nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "columns 1 and 4") {
+		t.Errorf("got %v, wanted an error for the duplicate name column", err)
+	}
+}
+
+func TestTransform_bom(t *testing.T) {
+	in := "\uFEFFsku,price,note\nABC-1,10,\n"
+	var out bytes.Buffer
+	err := Transform(strings.NewReader(in), &out, func(p *transformedPrice) (bool, error) {
+		if p.SKU != "ABC-1" {
+			t.Errorf("got sku %q", p.SKU)
+		}
+		p.Note = "kept"
+		return true, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "\uFEFFsku,price,note\nABC-1,10,kept\n"; out.String() != expected {
+		t.Errorf("got %q, wanted %q", out.String(), expected)
+	}
+}
+
+func TestTransform_missingColumn(t *testing.T) {
+	FailIfUnmatchedStructTags = false
+	defer func() { FailIfUnmatchedStructTags = true }()
+	var out bytes.Buffer
+	err := Transform(strings.NewReader("sku,price\nABC-1,10\n"), &out, func(p *transformedPrice) (bool, error) {
+		p.Note = "dropped"
+		return true, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "sku,price\nABC-1,10\n" {
+		t.Errorf("got %q", out.String())
+	}
+}
+
+func TestTransform_errors(t *testing.T) {
+	in := "sku,price,note\nABC-1,10,\nABC-2,20,\n"
+	fail := errors.New("fail")
+	err := Transform(strings.NewReader(in), &bytes.Buffer{}, func(p *transformedPrice) (bool, error) {
+		if p.SKU == "ABC-2" {
+			return false, fail
+		}
+		return true, nil
+	})
+	var rowErr *RowError
+	if !errors.As(err, &rowErr) || rowErr.Line != 3 || !errors.Is(err, fail) {
+		t.Errorf("got %v, wanted the error of f on line 3", err)
+	}
+
+	err = Transform(strings.NewReader(in), &bytes.Buffer{}, func(p *transformedPrice) (bool, error) {
+		p.Price = -p.Price
+		return true, nil
+	})
+	var ruleErr *RuleError
+	if !errors.As(err, &rowErr) || rowErr.Line != 2 || !errors.As(err, &ruleErr) || ruleErr.Rule != "min" {
+		t.Errorf("got %v, wanted the min rule broken on line 2", err)
+	}
+
+	if err := Transform(strings.NewReader(""), &bytes.Buffer{}, func(*transformedPrice) (bool, error) { return true, nil }); err != ErrEmptyCSV {
+		t.Errorf("got %v, wanted ErrEmptyCSV", err)
+	}
+}
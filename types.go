@@ -1,6 +1,7 @@
 package gocsv
 
 import (
+	"bytes"
 	"encoding"
 	"fmt"
 	"reflect"
@@ -295,6 +296,87 @@ func setField(field reflect.Value, value string, omitEmpty bool) error {
 	return nil
 }
 
+// setFieldBytes is setField for a value read by a Reader. Fields of the
+// predeclared bool, numeric and string types are parsed straight from the
+// bytes, other fields go through setField.
+func setFieldBytes(field reflect.Value, value []byte, omitEmpty bool, r *Reader, col int) error {
+	if field.Kind() == reflect.Ptr {
+		if omitEmpty && len(value) == 0 {
+			return nil
+		}
+		if field.IsNil() {
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+		field = field.Elem()
+	}
+	if t := field.Type(); t.PkgPath() != "" || t.Name() == "" {
+		return setField(field, string(value), omitEmpty) // Named or unnamed composite type
+	}
+
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(r.fieldString(col, value))
+	case reflect.Bool:
+		switch string(value) {
+		case "yes":
+			field.SetBool(true)
+		case "no", "":
+			field.SetBool(false)
+		default:
+			b, err := strconv.ParseBool(string(value))
+			if err != nil {
+				return err
+			}
+			field.SetBool(b)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value = bytes.TrimSpace(value)
+		if len(value) == 0 {
+			field.SetInt(0)
+			return nil
+		}
+		i, err := strconv.ParseInt(string(value), 10, 64)
+		if err != nil {
+			return err
+		}
+		field.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		value = bytes.TrimSpace(value)
+		if len(value) == 0 {
+			field.SetUint(0)
+			return nil
+		}
+		// support the float input
+		if bytes.IndexByte(value, '.') >= 0 {
+			f, err := strconv.ParseFloat(string(value), 64)
+			if err != nil {
+				return err
+			}
+			field.SetUint(uint64(f))
+			return nil
+		}
+		ui, err := strconv.ParseUint(string(value), 0, 64)
+		if err != nil {
+			return err
+		}
+		field.SetUint(ui)
+	case reflect.Float32, reflect.Float64:
+		value = bytes.TrimSpace(value)
+		if len(value) == 0 {
+			field.SetFloat(0)
+			return nil
+		}
+		f, err := strconv.ParseFloat(string(value), 64)
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	default:
+		return setField(field, string(value), omitEmpty)
+	}
+	return nil
+}
+
 func getFieldAsString(field reflect.Value) (str string, err error) {
 	switch field.Kind() {
 	case reflect.Interface:
@@ -14,16 +14,23 @@ type Unmarshaller struct {
 	MismatchedHeaders      []string
 	MismatchedStructFields []string
 	outType                reflect.Type
+	hooks                  decodeHooks
+	filters                rowFilter
+	opts                   decoderOptions
 }
 
 // NewUnmarshaller creates an unmarshaller from a csv.Reader and a struct.
-func NewUnmarshaller(reader *csv.Reader, out interface{}) (*Unmarshaller, error) {
+// Of the opts, only Filter and Where apply: Read skips the rows they filter out.
+func NewUnmarshaller(reader *csv.Reader, out interface{}, opts ...DecoderOption) (*Unmarshaller, error) {
 	headers, err := reader.Read()
 	if err != nil {
 		return nil, err
 	}
 
 	um := &Unmarshaller{reader: reader, outType: reflect.TypeOf(out)}
+	for _, opt := range opts {
+		opt(&um.opts)
+	}
 	err = validate(um, out, headers)
 	if err != nil {
 		return nil, err
@@ -34,16 +41,26 @@ func NewUnmarshaller(reader *csv.Reader, out interface{}) (*Unmarshaller, error)
 // Read returns an interface{} whose runtime type is the same as the struct that
 // was used to create the Unmarshaller.
 func (um *Unmarshaller) Read() (interface{}, error) {
-	row, err := um.reader.Read()
-	if err != nil {
-		return nil, err
+	for {
+		row, err := um.reader.Read()
+		if err != nil {
+			return nil, err
+		}
+		if !um.filters.keepRow(row) {
+			continue
+		}
+		line, _ := um.reader.FieldPos(0)
+		v, err := um.unmarshalRow(row, line)
+		if err != errRowFiltered {
+			return v, err
+		}
 	}
-	return um.unmarshalRow(row)
 }
 
 // validate ensures that a struct was used to create the Unmarshaller, and validates
 // CSV headers against the CSV tags in the struct.
 func validate(um *Unmarshaller, s interface{}, headers []string) error {
+	trimHeaderBOM(headers)
 	concreteType := reflect.TypeOf(s)
 	if concreteType.Kind() == reflect.Ptr {
 		concreteType = concreteType.Elem()
@@ -55,6 +72,9 @@ func validate(um *Unmarshaller, s interface{}, headers []string) error {
 	if len(structInfo.Fields) == 0 {
 		return errors.New("no csv struct tags found")
 	}
+	if err := structInfo.rulesError(); err != nil {
+		return err
+	}
 	csvHeadersLabels := make(map[int]*fieldInfo, len(structInfo.Fields)) // Used to store the corresponding header <-> position in CSV
 	headerCount := map[string]int{}
 	for i, csvColumnHeader := range headers {
@@ -71,14 +91,20 @@ func validate(um *Unmarshaller, s interface{}, headers []string) error {
 		return err
 	}
 
+	filters, err := newRowFilter(&um.opts, concreteType, headers)
+	if err != nil {
+		return err
+	}
+	um.filters = filters
 	um.fieldInfoMap = csvHeadersLabels
+	um.hooks = getDecodeHooks(concreteType)
 	um.MismatchedHeaders = mismatchHeaderFields(structInfo.Fields, headers)
 	um.MismatchedStructFields = mismatchStructFields(structInfo.Fields, headers)
 	return nil
 }
 
-// unmarshalRow converts a CSV row to a struct, based on CSV struct tags.
-func (um *Unmarshaller) unmarshalRow(row []string) (interface{}, error) {
+// unmarshalRow converts the CSV row read at line to a struct, based on CSV struct tags.
+func (um *Unmarshaller) unmarshalRow(row []string, line int) (interface{}, error) {
 	isPointer := false
 	concreteOutType := um.outType
 	if um.outType.Kind() == reflect.Ptr {
@@ -91,7 +117,16 @@ func (um *Unmarshaller) unmarshalRow(row []string) (interface{}, error) {
 			if err := setInnerField(&outValue, isPointer, fieldInfo.IndexChain, csvColumnContent, fieldInfo.omitEmpty); err != nil { // Set field of struct
 				return nil, fmt.Errorf("cannot assign field at %v to %s through index chain %v: %v", j, outValue.Type(), fieldInfo.IndexChain, err)
 			}
+			if err := fieldInfo.checkRules(csvColumnContent); err != nil {
+				return nil, &csv.ParseError{Line: line, Column: j + 1, Err: err}
+			}
 		}
 	}
+	if !um.filters.keepValue(outValue) {
+		return nil, errRowFiltered
+	}
+	if err := um.hooks.run(outValue, line); err != nil {
+		return nil, err
+	}
 	return outValue.Interface(), nil
 }
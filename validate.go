@@ -0,0 +1,144 @@
+package gocsv
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// fieldRule is a rule of the csv tag of a field, like max=150, that its
+// values must follow.
+type fieldRule struct {
+	name    string // min, max, minlen, maxlen, oneof or pattern
+	param   string
+	num     float64        // Bound of min, max, minlen and maxlen
+	values  []string       // Values of oneof
+	pattern *regexp.Regexp // Regexp of pattern, anchored as in a TableSchema
+}
+
+// isRuleOption reports whether the entry of a csv tag is a rule rather than a key.
+func isRuleOption(entry string) bool {
+	name, _, ok := strings.Cut(entry, "=")
+	if !ok {
+		return false
+	}
+	switch name {
+	case "min", "max", "minlen", "maxlen", "oneof", "pattern":
+		return true
+	}
+	return false
+}
+
+func parseFieldRule(entry string) (fieldRule, error) {
+	name, param, _ := strings.Cut(entry, "=")
+	rule := fieldRule{name: name, param: param}
+	var err error
+	switch name {
+	case "min", "max":
+		rule.num, err = strconv.ParseFloat(param, 64)
+	case "minlen", "maxlen":
+		var n int
+		if n, err = strconv.Atoi(param); err == nil && n < 0 {
+			err = fmt.Errorf("negative length %d", n)
+		}
+		rule.num = float64(n)
+	case "oneof":
+		rule.values = strings.Split(param, "|")
+	case "pattern":
+		rule.pattern, err = regexp.Compile("^(?:" + param + ")$")
+	}
+	return rule, err
+}
+
+// check returns whether value follows the rule. The bounds of min and max
+// are compared to the number trimmed of spaces, as the decoder reads it. An
+// empty value of an optional field, a pointer or tagged omitempty, only
+// breaks minlen: otherwise it breaks min and max, and oneof and pattern
+// unless they accept it.
+func (r *fieldRule) check(value string, optional bool) bool {
+	if value == "" && optional {
+		return r.name != "minlen" || r.num == 0
+	}
+	switch r.name {
+	case "min", "max":
+		n, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return false
+		}
+		if r.name == "min" {
+			return n >= r.num
+		}
+		return n <= r.num
+	case "minlen":
+		return float64(utf8.RuneCountInString(value)) >= r.num
+	case "maxlen":
+		return float64(utf8.RuneCountInString(value)) <= r.num
+	case "oneof":
+		for _, v := range r.values {
+			if value == v {
+				return true
+			}
+		}
+		return false
+	case "pattern":
+		return r.pattern.MatchString(value)
+	}
+	return true
+}
+
+// RuleError is returned when a value breaks a rule of the csv tag of its
+// field, as Rule=Param. Decoding wraps it in a *csv.ParseError, encoding
+// with ValidateRules in a *RowError.
+type RuleError struct {
+	Field string
+	Rule  string
+	Param string
+	Value string
+}
+
+func (e *RuleError) Error() string {
+	return fmt.Sprintf("field %s: value %q breaks rule %s=%s", e.Field, e.Value, e.Rule, e.Param)
+}
+
+// checkRules returns a *RuleError for the first rule of f that value breaks.
+func (f *fieldInfo) checkRules(value string) error {
+	for i := range f.rules {
+		if r := &f.rules[i]; !r.check(value, f.omitEmpty || f.pointer) {
+			return &RuleError{Field: f.getFirstKey(), Rule: r.name, Param: r.param, Value: value}
+		}
+	}
+	return nil
+}
+
+// rulesError returns the error of the first field of which the rules are invalid.
+func (s *structInfo) rulesError() error {
+	for _, f := range s.Fields {
+		if f.rulesErr != nil {
+			return f.rulesErr
+		}
+	}
+	return nil
+}
+
+// checkRowRules returns a *RowError for the first field of the row, written
+// at line, of which the value breaks a rule.
+func checkRowRules(info *structInfo, row []string, line int) error {
+	for j := range info.Fields {
+		if err := info.Fields[j].checkRules(row[j]); err != nil {
+			return &RowError{Line: line, Err: err}
+		}
+	}
+	return nil
+}
+
+// ValidateRules makes MarshalWithOptions and MarshalFromChan check the values
+// against the rules of the csv tags of their fields, as decoding does, and
+// fail with a *RowError wrapping a *RuleError instead of writing a row that
+// breaks one.
+func ValidateRules() EncoderOption {
+	return func(o *encoderOptions) {
+		o.validateRules = true
+	}
+}
@@ -0,0 +1,166 @@
+package gocsv
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type ruledItem struct {
+	Name   string  `csv:"name,minlen=1,maxlen=8"`
+	Age    int     `csv:"age,min=0,max=150"`
+	Price  float64 `csv:"price,omitempty,min=0.5"`
+	Status string  `csv:"status,oneof=active|closed"`
+	SKU    string  `csv:"sku,pattern=[A-Z]{3}-\\d{1,6}"`
+}
+
+func TestRules_parse(t *testing.T) {
+	info := getStructInfo(reflect.TypeOf(ruledItem{}))
+	var keys []string
+	for _, f := range info.Fields {
+		keys = append(keys, strings.Join(f.keys, "|"))
+	}
+	if got := strings.Join(keys, ","); got != "name,age,price,status,sku" {
+		t.Errorf("got keys %s", got)
+	}
+	if !info.Fields[2].omitEmpty || len(info.Fields[2].rules) != 1 {
+		t.Errorf("omitempty and min of price not parsed: %+v", info.Fields[2])
+	}
+	if sku := info.Fields[4].rules; len(sku) != 1 || sku[0].param != `[A-Z]{3}-\d{1,6}` {
+		t.Errorf("pattern with a separator not parsed: %+v", sku)
+	}
+}
+
+func TestRules_decode(t *testing.T) {
+	const header = "name,age,price,status,sku\n"
+	var items []ruledItem
+	if err := UnmarshalString(header+"widget,30,,active,ABC-12\n", &items); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		row    string
+		column int
+		rule   string
+	}{
+		{",30,1,active,ABC-1", 1, "minlen"},
+		{"a long name,30,1,active,ABC-1", 1, "maxlen"},
+		{"w,-1,1,active,ABC-1", 2, "min"},
+		{"w,151,1,active,ABC-1", 2, "max"},
+		{"w,30,0.1,active,ABC-1", 3, "min"},
+		{"w,30,1,open,ABC-1", 4, "oneof"},
+		{"w,30,1,active,abc-1", 5, "pattern"},
+		{"w,30,1,active,ABC-1234567", 5, "pattern"},
+	}
+	for _, test := range tests {
+		var parseErr *csv.ParseError
+		var ruleErr *RuleError
+		err := UnmarshalString(header+"widget,30,,active,ABC-12\n"+test.row+"\n", &items)
+		if !errors.As(err, &parseErr) || !errors.As(err, &ruleErr) {
+			t.Errorf("%s: got %v, wanted a *RuleError in a *csv.ParseError", test.row, err)
+			continue
+		}
+		if parseErr.Line != 3 || parseErr.Column != test.column || ruleErr.Rule != test.rule {
+			t.Errorf("%s: got %v, wanted rule %s on line 3, column %d", test.row, err, test.rule, test.column)
+		}
+	}
+
+	in := header + "w,151,1,active,ABC-1\n"
+	var ruleErr *RuleError
+	err := UnmarshalToChan(strings.NewReader(in), make(chan ruledItem, 1))
+	if !errors.As(err, &ruleErr) || ruleErr.Field != "age" || ruleErr.Param != "150" || ruleErr.Value != "151" {
+		t.Errorf("UnmarshalToChan: got %v", err)
+	}
+	um, err := NewUnmarshaller(csv.NewReader(strings.NewReader(in)), ruledItem{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := um.Read(); !errors.As(err, &ruleErr) || ruleErr.Rule != "max" {
+		t.Errorf("Unmarshaller: got %v", err)
+	}
+}
+
+func TestRules_emptyAndSpaces(t *testing.T) {
+	const header = "name,age,price,status,sku\n"
+	var items []ruledItem
+	if err := UnmarshalString(header+"w, 5 ,,active,ABC-1\n", &items); err != nil {
+		t.Errorf("spaces around a number: %v", err)
+	} else if items[0].Age != 5 {
+		t.Errorf("got age %d, wanted 5", items[0].Age)
+	}
+
+	type optional struct {
+		Age    *int   `csv:"age,min=1"`
+		Status string `csv:"status,omitempty,oneof=a|b"`
+	}
+	var optionals []optional
+	if err := UnmarshalString("age,status\n,\n", &optionals); err != nil {
+		t.Errorf("empty optional values: %v", err)
+	}
+
+	tests := []struct {
+		row  string
+		rule string
+	}{
+		{"w,,1,active,ABC-1", "min"},
+		{"w,30,1,,ABC-1", "oneof"},
+		{"w,30,1,active,", "pattern"},
+	}
+	for _, test := range tests {
+		var ruleErr *RuleError
+		err := UnmarshalString(header+test.row+"\n", &items)
+		if !errors.As(err, &ruleErr) || ruleErr.Rule != test.rule || ruleErr.Value != "" {
+			t.Errorf("%s: got %v, wanted the empty value to break %s", test.row, err, test.rule)
+		}
+	}
+}
+
+func TestRules_invalid(t *testing.T) {
+	type badItem struct {
+		Age int `csv:"age,min=zero"`
+	}
+	var items []badItem
+	err := UnmarshalString("age\n1\n", &items)
+	if err == nil || !strings.Contains(err.Error(), `invalid csv tag option "min=zero"`) {
+		t.Errorf("got %v", err)
+	}
+	if err := MarshalWithOptions(items, &bytes.Buffer{}, ValidateRules()); err == nil {
+		t.Error("invalid rule not reported by ValidateRules")
+	}
+	if err := Marshal(items, &bytes.Buffer{}); err != nil {
+		t.Errorf("rules checked without ValidateRules: %v", err)
+	}
+}
+
+func TestRules_encode(t *testing.T) {
+	items := []ruledItem{
+		{Name: "widget", Age: 30, Price: 1, Status: "active", SKU: "ABC-1"},
+		{Name: "gadget", Age: 200, Price: 1, Status: "active", SKU: "ABC-2"},
+	}
+	var out bytes.Buffer
+	if err := Marshal(items, &out); err != nil {
+		t.Fatalf("rules checked without ValidateRules: %v", err)
+	}
+
+	var rowErr *RowError
+	var ruleErr *RuleError
+	err := MarshalWithOptions(items, &bytes.Buffer{}, ValidateRules())
+	if !errors.As(err, &rowErr) || !errors.As(err, &ruleErr) || rowErr.Line != 3 || ruleErr.Rule != "max" {
+		t.Errorf("MarshalWithOptions: got %v, wanted a max *RuleError on line 3", err)
+	}
+
+	c := make(chan ruledItem, len(items))
+	for _, item := range items {
+		c <- item
+	}
+	close(c)
+	w := NewSafeCSVWriter(csv.NewWriter(&bytes.Buffer{}))
+	err = MarshalFromChan(context.Background(), c, w, ValidateRules())
+	if !errors.As(err, &rowErr) || !errors.As(err, &ruleErr) || rowErr.Line != 3 || ruleErr.Field != "age" {
+		t.Errorf("MarshalFromChan: got %v, wanted an age *RuleError on line 3", err)
+	}
+}
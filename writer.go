@@ -0,0 +1,265 @@
+package gocsv
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+)
+
+// CSVWriter is the interface of the writers a SafeCSVWriter can wrap,
+// implemented by csv.Writer and Writer.
+type CSVWriter interface {
+	Write(row []string) error
+	Flush()
+	Error() error
+}
+
+// QuotePolicy tells which fields a Writer quotes.
+type QuotePolicy int
+
+const (
+	// QuoteMinimal quotes only the fields holding special characters, as csv.Writer does.
+	QuoteMinimal QuotePolicy = iota
+	// QuoteAll quotes every field, including the empty ones.
+	QuoteAll
+	// QuoteNonNumeric quotes every field but the decimal numbers: the fields
+	// of numeric types when encoding structs, else the fields that look like
+	// numbers.
+	QuoteNonNumeric
+)
+
+var errNoQuoteEscape = errors.New("gocsv: cannot write a quote with neither DoubleQuote nor Escape")
+
+// Writer writes CSV records in a Dialect. It implements CSVWriter, and can
+// be used by the encoder through NewSafeWriter.
+type Writer struct {
+	// Quoting tells which fields are quoted, besides the ones holding
+	// special characters. Struct fields tagged `quote:"always"` are quoted
+	// whatever the policy. A csv.Writer ignores those tags, unless the
+	// SafeCSVWriter wrapping it has an Output.
+	Quoting QuotePolicy
+
+	d   *dialect
+	err error // Invalid dialect, reported by Write
+	w   *bufio.Writer
+
+	// The separators of d, as strings.
+	delim, quote, escape string
+
+	// quotedColumns are the columns always quoted, and columnKinds the kinds
+	// of the struct fields of the columns, set by the encoder.
+	quotedColumns []bool
+	columnKinds   []reflect.Kind
+}
+
+// NewWriter returns a new Writer that writes to out in the DefaultDialect.
+func NewWriter(out io.Writer) *Writer {
+	return NewDialectWriter(out, DefaultDialect())
+}
+
+// NewDialectWriter returns a new Writer that writes to out in the CSV format
+// described by d. Write fails if d is not valid.
+func NewDialectWriter(out io.Writer, d Dialect) *Writer {
+	compiled, err := d.compile()
+	w := &Writer{
+		d:   compiled,
+		err: err,
+		w:   bufio.NewWriter(out),
+	}
+	if err == nil {
+		w.delim, w.quote, w.escape = string(compiled.delim), string(compiled.quote), string(compiled.escape)
+	}
+	return w
+}
+
+// Write writes a single CSV record, followed by the RecordTerminator.
+// Writes are buffered, so Flush must eventually be called.
+func (w *Writer) Write(record []string) error {
+	if w.err != nil {
+		return w.err
+	}
+	for n, field := range record {
+		if n > 0 {
+			if _, err := w.w.Write(w.d.delim); err != nil {
+				return err
+			}
+		}
+		if !w.fieldNeedsQuotes(field) && !w.policyQuotes(n, field) {
+			if _, err := w.w.WriteString(field); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := w.writeQuoted(field); err != nil {
+			return err
+		}
+	}
+	_, err := w.w.Write(w.d.terminator)
+	return err
+}
+
+func (w *Writer) writeQuoted(field string) error {
+	if _, err := w.w.Write(w.d.quote); err != nil {
+		return err
+	}
+	for len(field) > 0 {
+		// Write everything up to the next quote or escape character at once.
+		i := indexAny(field, w.quote, w.escape)
+		if i < 0 {
+			i = len(field)
+		}
+		if _, err := w.w.WriteString(field[:i]); err != nil {
+			return err
+		}
+		field = field[i:]
+		if len(field) == 0 {
+			break
+		}
+		r, size := utf8.DecodeRuneInString(field)
+		prefix := w.d.escape // Escape the escape character itself, or the quote
+		if field[:size] == w.quote {
+			if w.d.doubleQuote {
+				prefix = w.d.quote
+			} else if prefix == nil {
+				return errNoQuoteEscape
+			}
+		}
+		if _, err := w.w.Write(prefix); err != nil {
+			return err
+		}
+		if _, err := w.w.WriteRune(r); err != nil {
+			return err
+		}
+		field = field[size:]
+	}
+	_, err := w.w.Write(w.d.quote)
+	return err
+}
+
+// indexAny returns the index of the first of the two separators in s, -1 if
+// there is none. An empty separator is never found.
+func indexAny(s string, a, b string) int {
+	i := -1
+	if a != "" {
+		i = strings.Index(s, a)
+	}
+	if b != "" {
+		if j := strings.Index(s, b); j >= 0 && (i < 0 || j < i) {
+			return j
+		}
+	}
+	return i
+}
+
+// fieldNeedsQuotes reports whether field must be quoted to be read back:
+// it holds the delimiter, the quote or escape characters or a line ending,
+// which any RecordTerminator is made of, or starts with a space, as for
+// csv.Writer.
+func (w *Writer) fieldNeedsQuotes(field string) bool {
+	if field == "" {
+		return false
+	}
+	if field == `\.` {
+		return true
+	}
+	if strings.Contains(field, w.delim) || indexAny(field, w.quote, w.escape) >= 0 || strings.ContainsAny(field, "\r\n") {
+		return true
+	}
+	return field[0] == ' ' || field[0] == '\t'
+}
+
+// policyQuotes reports whether the field of the column n must be quoted
+// because of the Quoting policy or a quote tag.
+func (w *Writer) policyQuotes(n int, field string) bool {
+	if n < len(w.quotedColumns) && w.quotedColumns[n] {
+		return true
+	}
+	switch w.Quoting {
+	case QuoteAll:
+		return true
+	case QuoteNonNumeric:
+		if n < len(w.columnKinds) && w.columnKinds[n] != reflect.Invalid {
+			return !isNumericKind(w.columnKinds[n])
+		}
+		return !isNumeric(field)
+	}
+	return false
+}
+
+func isNumericKind(k reflect.Kind) bool {
+	switch k {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// isNumeric reports whether s is a decimal number, such as -12, 3.5 or 1e-6.
+func isNumeric(s string) bool {
+	i := 0
+	if i < len(s) && (s[i] == '+' || s[i] == '-') {
+		i++
+	}
+	digits := 0
+	for ; i < len(s) && '0' <= s[i] && s[i] <= '9'; i++ {
+		digits++
+	}
+	if i < len(s) && s[i] == '.' {
+		for i++; i < len(s) && '0' <= s[i] && s[i] <= '9'; i++ {
+			digits++
+		}
+	}
+	if digits == 0 {
+		return false
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		i++
+		if i < len(s) && (s[i] == '+' || s[i] == '-') {
+			i++
+		}
+		start := i
+		for ; i < len(s) && '0' <= s[i] && s[i] <= '9'; i++ {
+		}
+		if i == start {
+			return false
+		}
+	}
+	return i == len(s)
+}
+
+// writeColumns writes record with the columns of p.
+func (w *Writer) writeColumns(record []string, p columnPolicy) error {
+	w.quotedColumns, w.columnKinds = p.quoted, p.kinds
+	defer func() { w.quotedColumns, w.columnKinds = nil, nil }()
+	return w.Write(record)
+}
+
+// Flush writes any buffered data to the underlying io.Writer.
+// To check if an error occurred during the Flush, call Error.
+func (w *Writer) Flush() {
+	w.w.Flush()
+}
+
+// Error reports any error that has occurred during a previous Write or Flush.
+func (w *Writer) Error() error {
+	if w.err != nil {
+		return w.err
+	}
+	_, err := w.w.Write(nil)
+	return err
+}
+
+// WriteAll writes multiple CSV records using Write and then calls Flush.
+func (w *Writer) WriteAll(records [][]string) error {
+	for _, record := range records {
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	return w.w.Flush()
+}
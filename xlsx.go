@@ -0,0 +1,546 @@
+package gocsv
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The XLSX files are read and written with the parts of the Office Open XML
+// format needed for a single table: a workbook of worksheets, their shared
+// strings, and the cell styles telling the dates from the other numbers.
+
+const (
+	xlsxMainNS = "http://schemas.openxmlformats.org/spreadsheetml/2006/main"
+	xlsxRelsNS = "http://schemas.openxmlformats.org/package/2006/relationships"
+	xlsxDocNS  = "http://schemas.openxmlformats.org/officeDocument/2006/relationships"
+	xmlHeader  = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>` + "\n"
+)
+
+// xlsxEpoch is the day 0 of the dates of Excel, as serial numbers of days.
+var xlsxEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+var xlsxStaticParts = []struct {
+	name, content string
+}{
+	{"[Content_Types].xml", xmlHeader + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", xmlHeader + `<Relationships xmlns="` + xlsxRelsNS + `">` +
+		`<Relationship Id="rId1" Type="` + xlsxDocNS + `/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/workbook.xml", xmlHeader + `<workbook xmlns="` + xlsxMainNS + `" xmlns:r="` + xlsxDocNS + `">` +
+		`<sheets><sheet name="Sheet1" sheetId="1" r:id="rId1"/></sheets>` +
+		`</workbook>`},
+	{"xl/_rels/workbook.xml.rels", xmlHeader + `<Relationships xmlns="` + xlsxRelsNS + `">` +
+		`<Relationship Id="rId1" Type="` + xlsxDocNS + `/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`<Relationship Id="rId2" Type="` + xlsxDocNS + `/styles" Target="styles.xml"/>` +
+		`</Relationships>`},
+	// The style 1 is the one of the dates.
+	{"xl/styles.xml", xmlHeader + `<styleSheet xmlns="` + xlsxMainNS + `">` +
+		`<numFmts count="1"><numFmt numFmtId="164" formatCode="yyyy-mm-dd hh:mm:ss"/></numFmts>` +
+		`<fonts count="1"><font><sz val="11"/><name val="Calibri"/></font></fonts>` +
+		`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+		`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+		`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+		`<cellXfs count="2"><xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+		`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/></cellXfs>` +
+		`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+		`</styleSheet>`},
+}
+
+// xlsxKind is the type of the cells of a column.
+type xlsxKind int
+
+const (
+	xlsxString xlsxKind = iota
+	xlsxNumber
+	xlsxBool
+	xlsxDate
+)
+
+// xlsxKindOf returns the type of the cells of a struct field of type t.
+func xlsxKindOf(t reflect.Type) xlsxKind {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return xlsxDate
+	}
+	p := reflect.PointerTo(t)
+	if p.Implements(marshallerType) || p.Implements(textMarshalerType) || p.Implements(stringerType) {
+		return xlsxString
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return xlsxBool
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return xlsxNumber
+	}
+	return xlsxString
+}
+
+// MarshalXLSX writes the interface as an XLSX workbook of a single worksheet
+// in the writer. The first row is the header, the cells of the numbers,
+// bools and time.Time fields are typed, the other values are strings.
+func MarshalXLSX(in interface{}, out io.Writer) error {
+	inValue, inType := getConcreteReflectValueAndType(in) // Get the concrete type (not pointer) (Slice<?> or Array<?>)
+	if err := ensureInType(inType); err != nil {
+		return err
+	}
+	_, inInnerType := getConcreteContainerInnerType(inType) // Get the concrete inner type (not pointer) (Container<"?">)
+	if err := ensureInInnerType(inInnerType); err != nil {
+		return err
+	}
+	inInnerStructInfo := getStructInfo(inInnerType) // Get the inner struct info to get CSV annotations
+	kinds := make([]xlsxKind, len(inInnerStructInfo.Fields))
+	for i, f := range inInnerStructInfo.Fields {
+		kinds[i] = xlsxKindOf(inInnerType.FieldByIndex(f.IndexChain).Type)
+	}
+
+	zw := zip.NewWriter(out)
+	for _, part := range xlsxStaticParts {
+		w, err := zw.Create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(w, part.content); err != nil {
+			return err
+		}
+	}
+	w, err := zw.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return err
+	}
+	sw := &xlsxSheetWriter{w: w}
+	sw.WriteString(xmlHeader + `<worksheet xmlns="` + xlsxMainNS + `"><sheetData>`)
+	sw.startRow(1)
+	for i, f := range inInnerStructInfo.Fields {
+		sw.stringCell(i, 1, f.getFirstKey())
+	}
+	sw.WriteString("</row>")
+	for i := 0; i < inValue.Len(); i++ {
+		row := i + 2
+		sw.startRow(row)
+		inInner := reflect.Indirect(inValue.Index(i))
+		for j, f := range inInnerStructInfo.Fields {
+			if err := sw.cell(j, row, kinds[j], inInner.FieldByIndex(f.IndexChain)); err != nil {
+				return &xlsxCellError{col: j, row: row, err: err}
+			}
+		}
+		sw.WriteString("</row>")
+	}
+	sw.WriteString("</sheetData></worksheet>")
+	if sw.err != nil {
+		return sw.err
+	}
+	return zw.Close()
+}
+
+type xlsxCellError struct {
+	col, row int
+	err      error
+}
+
+func (e *xlsxCellError) Error() string {
+	return fmt.Sprintf("cell %s: %v", xlsxCellRef(e.col, e.row), e.err)
+}
+
+func (e *xlsxCellError) Unwrap() error {
+	return e.err
+}
+
+// xlsxSheetWriter writes the XML of a worksheet, keeping the first error.
+type xlsxSheetWriter struct {
+	w   io.Writer
+	err error
+}
+
+func (sw *xlsxSheetWriter) WriteString(s string) {
+	if sw.err == nil {
+		_, sw.err = io.WriteString(sw.w, s)
+	}
+}
+
+func (sw *xlsxSheetWriter) startRow(row int) {
+	sw.WriteString(`<row r="` + strconv.Itoa(row) + `">`)
+}
+
+func (sw *xlsxSheetWriter) stringCell(col, row int, s string) {
+	var b bytes.Buffer
+	xml.EscapeText(&b, []byte(s))
+	sw.WriteString(`<c r="` + xlsxCellRef(col, row) + `" t="inlineStr"><is><t xml:space="preserve">` + b.String() + `</t></is></c>`)
+}
+
+// cell writes the cell of the field, nothing if the field is a nil pointer
+// or a zero time.
+func (sw *xlsxSheetWriter) cell(col, row int, kind xlsxKind, field reflect.Value) error {
+	for field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			return nil
+		}
+		field = field.Elem()
+	}
+	ref := xlsxCellRef(col, row)
+	switch kind {
+	case xlsxDate:
+		t := field.Interface().(time.Time)
+		if t.IsZero() {
+			return nil
+		}
+		sw.WriteString(`<c r="` + ref + `" s="1"><v>` + strconv.FormatFloat(xlsxSerial(t), 'f', -1, 64) + `</v></c>`)
+		return nil
+	case xlsxBool:
+		v := "0"
+		if field.Bool() {
+			v = "1"
+		}
+		sw.WriteString(`<c r="` + ref + `" t="b"><v>` + v + `</v></c>`)
+		return nil
+	}
+	s, err := getFieldAsString(field)
+	if err != nil {
+		return err
+	}
+	if kind == xlsxNumber && field.CanFloat() && (math.IsNaN(field.Float()) || math.IsInf(field.Float(), 0)) {
+		kind = xlsxString // Not a number Excel can store
+	}
+	if kind == xlsxNumber {
+		sw.WriteString(`<c r="` + ref + `"><v>` + s + `</v></c>`)
+	} else {
+		sw.stringCell(col, row, s)
+	}
+	return nil
+}
+
+// xlsxCellRef returns the reference of a cell, like "B3" for the column 1
+// and the row 3.
+func xlsxCellRef(col, row int) string {
+	var name []byte
+	for col++; col > 0; col = (col - 1) / 26 {
+		name = append([]byte{byte('A' + (col-1)%26)}, name...)
+	}
+	return string(name) + strconv.Itoa(row)
+}
+
+// xlsxColumn returns the column of a cell reference, -1 if it is invalid.
+func xlsxColumn(ref string) int {
+	col := 0
+	i := 0
+	for ; i < len(ref) && 'A' <= ref[i] && ref[i] <= 'Z'; i++ {
+		col = col*26 + int(ref[i]-'A'+1)
+	}
+	if i == 0 {
+		return -1
+	}
+	return col - 1
+}
+
+// xlsxSerial returns the serial number of the wall clock of t.
+func xlsxSerial(t time.Time) float64 {
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	return float64(wall.Sub(xlsxEpoch)) / float64(24*time.Hour)
+}
+
+// xlsxTime returns the time of a serial number, to the millisecond, in UTC.
+func xlsxTime(serial float64) time.Time {
+	ms := math.Round(serial * 24 * 60 * 60 * 1000)
+	return xlsxEpoch.Add(time.Duration(ms) * time.Millisecond)
+}
+
+// UnmarshalXLSX parses the worksheet named sheet of the XLSX workbook from the
+// reader in the interface, the first worksheet if sheet is empty. The first
+// row is the header. Date cells are read as RFC 3339 times, bool cells as
+// true or false. The workbook is read in memory unless in is an *os.File or
+// has a Size method, like *bytes.Reader.
+func UnmarshalXLSX(in io.Reader, sheet string, out interface{}) error {
+	zr, err := openXLSX(in)
+	if err != nil {
+		return err
+	}
+	sheetPath, err := xlsxSheetPath(zr, sheet)
+	if err != nil {
+		return err
+	}
+	shared, err := readXLSXSharedStrings(zr)
+	if err != nil {
+		return err
+	}
+	dates, err := readXLSXDateStyles(zr)
+	if err != nil {
+		return err
+	}
+	f, err := zr.Open(sheetPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return readTo(csvDecoder{&xlsxSheetReader{dec: xml.NewDecoder(f), shared: shared, dates: dates}}, out)
+}
+
+func openXLSX(in io.Reader) (*zip.Reader, error) {
+	if f, ok := in.(*os.File); ok {
+		info, err := f.Stat()
+		if err != nil {
+			return nil, err
+		}
+		return zip.NewReader(f, info.Size())
+	}
+	if r, ok := in.(interface {
+		io.ReaderAt
+		Size() int64
+	}); ok {
+		return zip.NewReader(r, r.Size())
+	}
+	b, err := io.ReadAll(in)
+	if err != nil {
+		return nil, err
+	}
+	return zip.NewReader(bytes.NewReader(b), int64(len(b)))
+}
+
+func readXLSXPart(zr *zip.Reader, name string, v interface{}) (bool, error) {
+	f, err := zr.Open(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	return true, xml.NewDecoder(f).Decode(v)
+}
+
+// xlsxSheetPath returns the path in the archive of the worksheet named sheet.
+func xlsxSheetPath(zr *zip.Reader, sheet string) (string, error) {
+	var workbook struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			ID   string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if ok, err := readXLSXPart(zr, "xl/workbook.xml", &workbook); err != nil {
+		return "", err
+	} else if !ok {
+		return "", errors.New("not an XLSX workbook")
+	}
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if _, err := readXLSXPart(zr, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return "", err
+	}
+	for _, s := range workbook.Sheets {
+		if sheet != "" && s.Name != sheet {
+			continue
+		}
+		for _, rel := range rels.Relationships {
+			if rel.ID == s.ID {
+				if strings.HasPrefix(rel.Target, "/") {
+					return rel.Target[1:], nil
+				}
+				return path.Join("xl", rel.Target), nil
+			}
+		}
+		return "", fmt.Errorf("worksheet %q not found in the workbook", s.Name)
+	}
+	if sheet == "" {
+		return "", errors.New("no worksheet in the workbook")
+	}
+	return "", fmt.Errorf("no worksheet named %q", sheet)
+}
+
+// xlsxText is a rich text, of several runs, or a plain one.
+type xlsxText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t *xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.T
+	}
+	var b strings.Builder
+	for _, r := range t.Runs {
+		b.WriteString(r.T)
+	}
+	return b.String()
+}
+
+func readXLSXSharedStrings(zr *zip.Reader) ([]string, error) {
+	var sst struct {
+		Items []xlsxText `xml:"si"`
+	}
+	if _, err := readXLSXPart(zr, "xl/sharedStrings.xml", &sst); err != nil {
+		return nil, err
+	}
+	shared := make([]string, len(sst.Items))
+	for i := range sst.Items {
+		shared[i] = sst.Items[i].String()
+	}
+	return shared, nil
+}
+
+// readXLSXDateStyles returns which cell styles are date formats.
+func readXLSXDateStyles(zr *zip.Reader) ([]bool, error) {
+	var styles struct {
+		NumFmts []struct {
+			ID   int    `xml:"numFmtId,attr"`
+			Code string `xml:"formatCode,attr"`
+		} `xml:"numFmts>numFmt"`
+		CellXfs []struct {
+			NumFmtID int `xml:"numFmtId,attr"`
+		} `xml:"cellXfs>xf"`
+	}
+	if _, err := readXLSXPart(zr, "xl/styles.xml", &styles); err != nil {
+		return nil, err
+	}
+	customDates := make(map[int]bool)
+	for _, f := range styles.NumFmts {
+		customDates[f.ID] = isDateFormat(f.Code)
+	}
+	dates := make([]bool, len(styles.CellXfs))
+	for i, xf := range styles.CellXfs {
+		id := xf.NumFmtID
+		dates[i] = (14 <= id && id <= 22) || (27 <= id && id <= 36) || (45 <= id && id <= 47) || (50 <= id && id <= 58) || customDates[id]
+	}
+	return dates, nil
+}
+
+// isDateFormat reports whether the number format code formats dates or times.
+func isDateFormat(code string) bool {
+	inQuotes, inBrackets := false, false
+	for i := 0; i < len(code); i++ {
+		c := code[i]
+		switch {
+		case c == '"':
+			inQuotes = !inQuotes
+		case inQuotes:
+		case c == '\\':
+			i++
+		case c == '[':
+			inBrackets = true
+		case c == ']':
+			inBrackets = false
+		case inBrackets:
+		case strings.IndexByte("ymdhsYMDHS", c) >= 0:
+			return true
+		}
+	}
+	return false
+}
+
+type xlsxCell struct {
+	Ref    string    `xml:"r,attr"`
+	Type   string    `xml:"t,attr"`
+	Style  int       `xml:"s,attr"`
+	Value  string    `xml:"v"`
+	Inline *xlsxText `xml:"is"`
+}
+
+// xlsxSheetReader is a CSVReader of the rows of a worksheet, read as they are decoded.
+type xlsxSheetReader struct {
+	dec    *xml.Decoder
+	shared []string
+	dates  []bool
+}
+
+func (r *xlsxSheetReader) Read() ([]string, error) {
+	for {
+		token, err := r.dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "row" {
+			continue
+		}
+		var row struct {
+			Cells []xlsxCell `xml:"c"`
+		}
+		if err := r.dec.DecodeElement(&row, &start); err != nil {
+			return nil, err
+		}
+		var record []string
+		for i, c := range row.Cells {
+			col := i
+			if c.Ref != "" {
+				if col = xlsxColumn(c.Ref); col < 0 {
+					return nil, fmt.Errorf("invalid cell reference %q", c.Ref)
+				}
+			}
+			for len(record) <= col {
+				record = append(record, "")
+			}
+			value, err := r.value(&c)
+			if err != nil {
+				return nil, fmt.Errorf("cell %s: %v", c.Ref, err)
+			}
+			record[col] = value
+		}
+		return record, nil
+	}
+}
+
+func (r *xlsxSheetReader) ReadAll() ([][]string, error) {
+	var records [][]string
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return records, nil
+		} else if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+}
+
+// value returns the text of the cell.
+func (r *xlsxSheetReader) value(c *xlsxCell) (string, error) {
+	switch c.Type {
+	case "s":
+		i, err := strconv.Atoi(c.Value)
+		if err != nil || i < 0 || i >= len(r.shared) {
+			return "", fmt.Errorf("invalid shared string %q", c.Value)
+		}
+		return r.shared[i], nil
+	case "inlineStr":
+		if c.Inline == nil {
+			return "", nil
+		}
+		return c.Inline.String(), nil
+	case "b":
+		return strconv.FormatBool(c.Value == "1"), nil
+	case "", "n":
+		if c.Value != "" && c.Style >= 0 && c.Style < len(r.dates) && r.dates[c.Style] {
+			serial, err := strconv.ParseFloat(c.Value, 64)
+			if err != nil {
+				return "", err
+			}
+			return xlsxTime(serial).Format(time.RFC3339Nano), nil
+		}
+	}
+	return c.Value, nil // Formula strings, errors and numbers
+}
@@ -0,0 +1,131 @@
+package gocsv
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type xlsxSample struct {
+	Name    string     `csv:"name"`
+	Count   int        `csv:"count"`
+	Ratio   float64    `csv:"ratio"`
+	Active  bool       `csv:"active"`
+	Created time.Time  `csv:"created"`
+	Updated *time.Time `csv:"updated"`
+}
+
+func TestMarshalXLSX(t *testing.T) {
+	created := time.Date(2024, 2, 29, 13, 45, 30, 0, time.UTC)
+	samples := []xlsxSample{
+		{Name: "a <b> & c", Count: 3, Ratio: 0.25, Active: true, Created: created, Updated: &created},
+		{Name: " spaced ", Count: -1, Created: created.AddDate(-30, 0, 0)},
+	}
+	b := bytes.Buffer{}
+	if err := MarshalXLSX(samples, &b); err != nil {
+		t.Fatal(err)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := zr.Open("xl/worksheets/sheet1.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	sheet := bytes.Buffer{}
+	sheet.ReadFrom(f)
+	for _, cell := range []string{
+		`<c r="A2" t="inlineStr"><is><t xml:space="preserve">a &lt;b&gt; &amp; c</t></is></c>`,
+		`<c r="B2"><v>3</v></c>`,
+		`<c r="D2" t="b"><v>1</v></c>`,
+		`<c r="E2" s="1"><v>45351.57326388889</v></c>`,
+		`<c r="E3" s="1">`,
+	} {
+		if !strings.Contains(sheet.String(), cell) {
+			t.Errorf("expected %s in the worksheet %s", cell, sheet.String())
+		}
+	}
+	if strings.Contains(sheet.String(), `r="F3"`) {
+		t.Error("expected no cell for a nil pointer")
+	}
+
+	var decoded []xlsxSample
+	if err := UnmarshalXLSX(&b, "", &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 2 {
+		t.Fatalf("unexpected samples %+v", decoded)
+	}
+	if decoded[0].Name != samples[0].Name || decoded[0].Count != 3 || decoded[0].Ratio != 0.25 || !decoded[0].Active ||
+		!decoded[0].Created.Equal(created) || !decoded[0].Updated.Equal(created) {
+		t.Errorf("unexpected sample %+v", decoded[0])
+	}
+	if decoded[1].Name != " spaced " || decoded[1].Count != -1 || !decoded[1].Created.Equal(samples[1].Created) || decoded[1].Updated != nil {
+		t.Errorf("unexpected sample %+v", decoded[1])
+	}
+}
+
+// xlsxWorkbook returns an XLSX workbook of the given parts.
+func xlsxWorkbook(t *testing.T, parts map[string]string) *bytes.Reader {
+	b := bytes.Buffer{}
+	zw := zip.NewWriter(&b)
+	for name, content := range parts {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return bytes.NewReader(b.Bytes())
+}
+
+func TestUnmarshalXLSX(t *testing.T) {
+	workbook := xlsxWorkbook(t, map[string]string{
+		"xl/workbook.xml": `<workbook xmlns="` + xlsxMainNS + `" xmlns:r="` + xlsxDocNS + `"><sheets>` +
+			`<sheet name="Notes" sheetId="1" r:id="rId1"/><sheet name="Data" sheetId="2" r:id="rId2"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships xmlns="` + xlsxRelsNS + `">` +
+			`<Relationship Id="rId1" Target="worksheets/sheet1.xml"/>` +
+			`<Relationship Id="rId2" Target="/xl/worksheets/data.xml"/></Relationships>`,
+		"xl/sharedStrings.xml": `<sst xmlns="` + xlsxMainNS + `"><si><t>name</t></si><si><t>count</t></si>` +
+			`<si><r><t>rich </t></r><r><t>text</t></r></si><si><t>created</t></si></sst>`,
+		"xl/styles.xml": `<styleSheet xmlns="` + xlsxMainNS + `"><numFmts><numFmt numFmtId="170" formatCode="&quot;day&quot; d/m/yyyy"/>` +
+			`<numFmt numFmtId="171" formatCode="0.00&quot;h&quot;"/></numFmts>` +
+			`<cellXfs><xf numFmtId="0"/><xf numFmtId="170"/><xf numFmtId="171"/><xf numFmtId="14"/></cellXfs></styleSheet>`,
+		"xl/worksheets/sheet1.xml": `<worksheet xmlns="` + xlsxMainNS + `"><sheetData><row r="1"><c r="A1" t="inlineStr"><is><t>x</t></is></c></row></sheetData></worksheet>`,
+		"xl/worksheets/data.xml": `<worksheet xmlns="` + xlsxMainNS + `"><sheetData>` +
+			`<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="D1" t="s"><v>3</v></c></row>` +
+			`<row r="2"><c r="A2" t="s"><v>2</v></c><c r="B2" s="2"><v>12</v></c><c r="D2" s="1"><v>45351.5</v></c></row>` +
+			`<row r="3"><c r="B3"><v>x</v></c><c r="D3" s="3"><v>1</v></c></row>` +
+			`</sheetData></worksheet>`,
+	})
+
+	type sample struct {
+		Name    string    `csv:"name"`
+		Count   int       `csv:"count"`
+		Created time.Time `csv:"created"`
+	}
+	var samples []sample
+	err := UnmarshalXLSX(workbook, "Data", &samples)
+	var parseErr *csv.ParseError
+	if !errors.As(err, &parseErr) || parseErr.Line != 3 || parseErr.Column != 2 {
+		t.Fatalf("expected an error on line 3, column 2, got %v", err)
+	}
+	if len(samples) < 1 || samples[0].Name != "rich text" || samples[0].Count != 12 ||
+		!samples[0].Created.Equal(time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected samples %+v", samples)
+	}
+
+	if err := UnmarshalXLSX(workbook, "Missing", &samples); err == nil || !strings.Contains(err.Error(), "Missing") {
+		t.Fatalf("expected a missing worksheet error, got %v", err)
+	}
+}